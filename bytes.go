@@ -0,0 +1,33 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import "unsafe"
+
+// MatchBytes is a version of MatchTo that matches a path given as a byte
+// slice. The path is not copied or converted to a string; it is matched in
+// place and must not be modified until MatchBytes returns.
+//
+// Matches is a pointer to a slice that needs to have enough match capacity.
+// Vars, if not nil, is a pointer to a map into which parsed variables will be
+// stored into. Variable values are the only thing copied from path. If vars
+// is nil variables are not parsed.
+// Returns a boolean denoting if anything was matched.
+func (vr *Varouter) MatchBytes(path []byte, matches *[]string, vars *Vars) bool {
+	// The matcher walks the tree by string, so path is viewed as one. The
+	// view is valid only until MatchBytes returns and must not be stored:
+	// matches hold registered templates, variable values are cloned and
+	// results are not cached.
+	var view = unsafe.String(unsafe.SliceData(path), len(path))
+	var state = matchState{
+		current:  vr.root,
+		path:     view,
+		length:   len(view),
+		matches:  matches,
+		vars:     vars,
+		copyvars: true,
+	}
+	return vr.matchPath(&state)
+}
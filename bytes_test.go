@@ -0,0 +1,78 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchBytes(t *testing.T) {
+	vr := New()
+	if err := vr.Register("/home/:user/.config/:application"); err != nil {
+		t.Fatal(err)
+	}
+	path := []byte("/home/vedran/.config/myapp")
+	matches := make([]string, 0, 8)
+	vars := make(Vars)
+	if !vr.MatchBytes(path, &matches, &vars) {
+		t.Fatal("MatchBytes failed.")
+	}
+	copy(path, "/home/xxxxxx/.config/xxxxx")
+	if fmt.Sprint(matches, vars) != "[/home/:user/.config/:application] map[application:myapp user:vedran]" {
+		t.Fatalf("MatchBytes failed: '%v', '%v'", matches, vars)
+	}
+	matches = matches[:0]
+	if !vr.MatchBytes(path, &matches, nil) {
+		t.Fatal("MatchBytes without vars failed.")
+	}
+}
+
+func TestMatchBytesAllocs(t *testing.T) {
+	vr := New()
+	vr.Register("/home/:user/.config/:application")
+	path := []byte("/home/vedran/.config/myapp")
+	matches := make([]string, 0, 8)
+	vars := make(Vars)
+	if n := testing.AllocsPerRun(100, func() {
+		matches = matches[:0]
+		vr.MatchBytes(path, &matches, nil)
+	}); n > 0 {
+		t.Fatalf("MatchBytes allocated %v times", n)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		matches = matches[:0]
+		vr.MatchBytes(path, &matches, &vars)
+	}); n > 2 {
+		t.Fatalf("MatchBytes allocated %v times, expected at most one per variable", n)
+	}
+}
+
+func BenchmarkMatchBytes(b *testing.B) {
+	vr := New()
+	vr.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	path := []byte("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	matches := make([]string, 0, 8)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matches = matches[:0]
+		vr.MatchBytes(path, &matches, nil)
+	}
+}
+
+func BenchmarkMatchBytes_Variables(b *testing.B) {
+	vr := New()
+	vr.Register("/home/:user/.config/:application")
+	path := []byte("/home/vedran/.config/myapp")
+	matches := make([]string, 0, 8)
+	vars := make(Vars)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matches = matches[:0]
+		vr.MatchBytes(path, &matches, &vars)
+	}
+}
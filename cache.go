@@ -0,0 +1,130 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// matchCache is a bounded least recently used cache of Match results.
+// It is safe for concurrent use.
+type matchCache struct {
+	hits   uint64 // hits is the number of cache hits.
+	misses uint64 // misses is the number of cache misses.
+
+	mu      sync.Mutex               // mu protects the fields below.
+	size    int                      // size is the maximum number of entries.
+	entries map[string]*list.Element // entries maps paths to list elements.
+	order   *list.List               // order holds *cacheEntry, most recently used first.
+}
+
+// cacheEntry is a cached Match result.
+type cacheEntry struct {
+	path    string   // path is the matched path.
+	matches []string // matches are the matched templates.
+	vars    Vars     // vars are the parsed variables.
+	matched bool     // matched is the match result.
+}
+
+// newMatchCache returns a new *matchCache of the specified size.
+func newMatchCache(size int) *matchCache {
+	return &matchCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// get returns copies of the cached results for path and a truth if found.
+func (mc *matchCache) get(path string) (matches []string, vars Vars, matched, ok bool) {
+	mc.mu.Lock()
+	var elem, exists = mc.entries[path]
+	if !exists {
+		mc.mu.Unlock()
+		atomic.AddUint64(&mc.misses, 1)
+		return
+	}
+	mc.order.MoveToFront(elem)
+	var entry = elem.Value.(*cacheEntry)
+	mc.mu.Unlock()
+	atomic.AddUint64(&mc.hits, 1)
+	matches, vars = copyResults(entry.matches, entry.vars)
+	return matches, vars, entry.matched, true
+}
+
+// put stores copies of results for path, evicting the least recently used
+// entry if the cache is full.
+func (mc *matchCache) put(path string, matches []string, vars Vars, matched bool) {
+	var entry = &cacheEntry{path: path, matched: matched}
+	entry.matches, entry.vars = copyResults(matches, vars)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if elem, exists := mc.entries[path]; exists {
+		elem.Value = entry
+		mc.order.MoveToFront(elem)
+		return
+	}
+	if mc.order.Len() >= mc.size {
+		var last = mc.order.Back()
+		delete(mc.entries, last.Value.(*cacheEntry).path)
+		mc.order.Remove(last)
+	}
+	mc.entries[path] = mc.order.PushFront(entry)
+}
+
+// purge removes all entries from the cache.
+func (mc *matchCache) purge() {
+	mc.mu.Lock()
+	mc.entries = make(map[string]*list.Element, mc.size)
+	mc.order.Init()
+	mc.mu.Unlock()
+}
+
+// copyResults returns copies of matches and vars.
+func copyResults(matches []string, vars Vars) ([]string, Vars) {
+	var m []string
+	if matches != nil {
+		m = make([]string, len(matches))
+		copy(m, matches)
+	}
+	var v = make(Vars, len(vars))
+	for key, val := range vars {
+		v[key] = val
+	}
+	return m, v
+}
+
+// SetCache enables a cache of Match results for up to size most recently
+// matched paths. If size is less than one the cache is disabled. Setting the
+// cache discards previously cached results.
+//
+// Cached results are discarded when a template is registered. Match returns
+// results the caller may modify, so a cache hit returns copies of cached
+// results which costs an allocation of the matches slice and of the vars
+// map. Hits still avoid walking the template tree and caching pays off when
+// few distinct paths are matched often.
+//
+// The cache is safe for concurrent use by Match. SetCache is not safe for
+// concurrent use with Match. For matching from multiple goroutines while
+// templates are no longer registered use Compile; the returned Matcher is
+// safe for concurrent use and gets its own cache of the same size.
+func (vr *Varouter) SetCache(size int) {
+	if size < 1 {
+		vr.cache = nil
+		return
+	}
+	vr.cache = newMatchCache(size)
+}
+
+// CacheStats returns the number of Match cache hits and misses since the
+// cache was set. It returns zeros if the cache is disabled.
+func (vr *Varouter) CacheStats() (hits, misses uint64) {
+	if vr.cache == nil {
+		return 0, 0
+	}
+	return atomic.LoadUint64(&vr.cache.hits), atomic.LoadUint64(&vr.cache.misses)
+}
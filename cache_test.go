@@ -0,0 +1,123 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	vr := New()
+	vr.SetCache(2)
+	vr.Register("/home/:user")
+	for i := 0; i < 2; i++ {
+		matches, vars, matched := vr.Match("/home/vedran")
+		if !matched || fmt.Sprint(matches, vars) != "[/home/:user] map[user:vedran]" {
+			t.Fatalf("Cached Match failed: '%v', '%v'", matches, vars)
+		}
+		matches[0] = ""
+		vars["user"] = ""
+	}
+	if hits, misses := vr.CacheStats(); hits != 1 || misses != 1 {
+		t.Fatalf("CacheStats failed: '%d', '%d'", hits, misses)
+	}
+	if err := vr.Register("/+"); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _, _ := vr.Match("/home/vedran"); len(matches) != 2 {
+		t.Fatalf("Register failed to invalidate cache: '%v'", matches)
+	}
+	vr.Match("a")
+	vr.Match("b")
+	vr.Match("/home/vedran")
+	if hits, misses := vr.CacheStats(); hits != 1 || misses != 5 {
+		t.Fatalf("Cache eviction failed: '%d', '%d'", hits, misses)
+	}
+	if _, _, matched := vr.Match("b"); matched {
+		t.Fatal("Cached Match failed.")
+	}
+	if hits, _ := vr.CacheStats(); hits != 2 {
+		t.Fatal("Cache failed to cache a negative result.")
+	}
+	vr.SetCache(0)
+	if hits, misses := vr.CacheStats(); hits != 0 || misses != 0 {
+		t.Fatal("SetCache failed to disable cache.")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	vr := New()
+	vr.SetCache(4)
+	vr.Register("/home/:user")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i)
+			for j := 0; j < 1000; j++ {
+				if _, vars, matched := vr.Match("/home/" + user); !matched || vars["user"] != user {
+					t.Errorf("Concurrent cached Match failed: '%v'", vars)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if hits, misses := vr.CacheStats(); hits+misses != 8000 {
+		t.Fatalf("Concurrent CacheStats failed: '%d', '%d'", hits, misses)
+	}
+}
+
+func TestCacheMatcher(t *testing.T) {
+	vr := New()
+	vr.SetCache(4)
+	vr.Register("/home/:user")
+	vr.Match("/home/vedran")
+	m := vr.Compile()
+	if hits, misses := m.CacheStats(); hits != 0 || misses != 0 {
+		t.Fatalf("Matcher cache is not empty: '%d', '%d'", hits, misses)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i%2)
+			for j := 0; j < 1000; j++ {
+				if _, vars, matched := m.Match("/home/" + user); !matched || vars["user"] != user {
+					t.Errorf("Concurrent cached Matcher Match failed: '%v'", vars)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if hits, misses := m.CacheStats(); hits+misses != 8000 || hits < 8000-8 {
+		t.Fatalf("Matcher CacheStats failed: '%d', '%d'", hits, misses)
+	}
+}
+
+func BenchmarkMatchCached(b *testing.B) {
+	vl := New()
+	vl.SetCache(256)
+	vl.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vl.Match("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	}
+}
+
+func BenchmarkMatchCached_Prefixes(b *testing.B) {
+	vr := New()
+	vr.SetCache(256)
+	registerPrefixBench(vr, "/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vr.Match("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	}
+}
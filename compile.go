@@ -0,0 +1,187 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"sort"
+	"strings"
+)
+
+// compileSliceMax is the maximum number of sub elements of a compiled element
+// stored in a sorted slice instead of a map.
+const compileSliceMax = 16
+
+// Matcher is an immutable, compiled form of Varouter returned by Compile.
+//
+// Sub elements of compiled template levels with few sub elements are stored
+// in slices sorted by name and lookups are made using binary search.
+//
+// Matcher is safe for concurrent use without locking.
+type Matcher struct {
+	vr Varouter // vr is the Varouter holding the compiled tree.
+}
+
+// Compile returns a *Matcher that matches paths against templates registered
+// at the time of the call. Templates registered afterwards do not affect the
+// returned Matcher.
+//
+// Element names and templates are interned into a single string in the
+// compiled tree which retains no references to registered templates.
+//
+// If a Match cache was set using SetCache the Matcher gets its own, empty
+// cache of the same size.
+//
+// Compile is not safe for concurrent use with Register.
+func (vr *Varouter) Compile() *Matcher {
+	var in = make(interner)
+	in.collect(vr.root)
+	in.build()
+	var m = &Matcher{
+		vr: Varouter{
+			count:        vr.count,
+			overrides:    vr.overrides,
+			root:         compileElement(vr.root, in),
+			backend:      vr.backend,
+			compressed:   vr.compressed,
+			override:     vr.override,
+			exclusion:    vr.exclusion,
+			separator:    vr.separator,
+			variable:     vr.variable,
+			prefix:       vr.prefix,
+			wildcardone:  vr.wildcardone,
+			wildcardmany: vr.wildcardmany,
+		},
+	}
+	if vr.cache != nil {
+		m.vr.cache = newMatchCache(vr.cache.size)
+	}
+	if len(vr.priorities) > 0 {
+		m.vr.priorities = make(map[string]int, len(vr.priorities))
+		for template, priority := range vr.priorities {
+			m.vr.priorities[in[template]] = priority
+		}
+	}
+	return m
+}
+
+// compileElement returns a compiled copy of the element tree of e with
+// strings interned by in.
+func compileElement(e *element, in interner) *element {
+	var c = *e
+	c.name = in[e.name]
+	c.tail = in[e.tail]
+	c.template = in[e.template]
+	c.hasvariable = in[e.hasvariable]
+	c.subs = nil
+	c.wildcards = nil
+	c.prefixes = nil
+	if len(e.subs) == 0 {
+		return &c
+	}
+	var subs = make([]*element, 0, len(e.subs))
+	for _, sub := range e.subs {
+		subs = append(subs, compileElement(sub, in))
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].name < subs[j].name })
+	if len(subs) <= compileSliceMax {
+		c.literals = subs
+	} else {
+		c.subs = make(elements, len(subs))
+		for _, sub := range subs {
+			c.subs[sub.name] = sub
+		}
+	}
+	for _, sub := range subs {
+		if sub.iswildcard {
+			c.wildcards = append(c.wildcards, sub)
+		}
+		if sub.isprefix {
+			if c.prefixes == nil {
+				c.prefixes = &prefixNode{}
+			}
+			c.prefixes.insert(sub)
+		}
+	}
+	return &c
+}
+
+// interner maps strings to their interned copies.
+type interner map[string]string
+
+// collect adds strings of the element tree of e to in.
+func (in interner) collect(e *element) {
+	in[e.name] = ""
+	in[e.tail] = ""
+	in[e.template] = ""
+	in[e.hasvariable] = ""
+	for _, sub := range e.subs {
+		in.collect(sub)
+	}
+}
+
+// build copies collected strings to a single string and maps them to their
+// substrings.
+func (in interner) build() {
+	var keys = make([]string, 0, len(in))
+	var size int
+	for key := range in {
+		keys = append(keys, key)
+		size += len(key)
+	}
+	var sb strings.Builder
+	sb.Grow(size)
+	for _, key := range keys {
+		sb.WriteString(key)
+	}
+	var arena = sb.String()
+	var offset int
+	for _, key := range keys {
+		in[key] = arena[offset : offset+len(key)]
+		offset += len(key)
+	}
+}
+
+// Match matches a path against compiled templates.
+// See Varouter.Match for details.
+func (m *Matcher) Match(path string) (matches []string, vars Vars, matched bool) {
+	return m.vr.Match(path)
+}
+
+// MatchTo matches a path against compiled templates.
+// See Varouter.MatchTo for details.
+func (m *Matcher) MatchTo(path *string, matches *[]string, vars *Vars) bool {
+	return m.vr.MatchTo(path, matches, vars)
+}
+
+// MatchTop matches a path against compiled templates.
+// See Varouter.MatchTop for details.
+func (m *Matcher) MatchTop(path string) (template string, vars Vars, matched bool) {
+	return m.vr.MatchTop(path)
+}
+
+// MatchOne matches a path against compiled templates.
+// See Varouter.MatchOne for details.
+func (m *Matcher) MatchOne(path string) (template string, vars Vars, matched bool) {
+	return m.vr.MatchOne(path)
+}
+
+// MatchWith matches a path against compiled templates.
+// See Varouter.MatchWith for details.
+func (m *Matcher) MatchWith(path string, mc *MatchContext) bool {
+	return m.vr.MatchWith(path, mc)
+}
+
+// MatchBytes matches a path against compiled templates.
+// See Varouter.MatchBytes for details.
+func (m *Matcher) MatchBytes(path []byte, matches *[]string, vars *Vars) bool {
+	return m.vr.MatchBytes(path, matches, vars)
+}
+
+// CacheStats returns the number of Match cache hits and misses of the
+// Matcher. See Varouter.CacheStats for details.
+func (m *Matcher) CacheStats() (hits, misses uint64) { return m.vr.CacheStats() }
+
+// NumTemplates returns number of compiled templates.
+func (m *Matcher) NumTemplates() int { return m.vr.count }
@@ -0,0 +1,102 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	vr := New()
+	for _, template := range []string{
+		"/+",
+		"/a+",
+		"/ab+",
+		"/a*",
+		"/?b",
+		"/*",
+	} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := vr.Compile()
+	if err := vr.Register("/b"); err != nil {
+		t.Fatal(err)
+	}
+	if m.NumTemplates() == vr.NumTemplates() {
+		t.Fatal("Compiled Matcher changed after Register.")
+	}
+	for i := 0; i < 8; i++ {
+		matches, _, matched := m.Match("/ab")
+		if !matched || fmt.Sprint(matches) != "[/* /?b /a* /+ /a+ /ab+]" {
+			t.Fatalf("Compiled Match order failed: '%v'", matches)
+		}
+	}
+	if template, _, _ := m.MatchTop("/b"); template == "/b" {
+		t.Fatal("Compiled Match matched a template registered after Compile.")
+	}
+	if template, _, matched := m.MatchOne("/ab"); !matched || template != "/ab+" {
+		t.Fatalf("Compiled MatchOne failed: '%s'", template)
+	}
+}
+
+func TestCompileLarge(t *testing.T) {
+	vr := New()
+	for i := 0; i < compileSliceMax*2; i++ {
+		vr.Register(fmt.Sprintf("/%d/:id", i))
+	}
+	m := vr.Compile()
+	for i := 0; i < compileSliceMax*2; i++ {
+		if _, vars, matched := m.Match(fmt.Sprintf("/%d/%d", i, i)); !matched || vars["id"] != fmt.Sprint(i) {
+			t.Fatalf("Compiled Match failed: '%d'", i)
+		}
+	}
+}
+
+func TestCompileConcurrent(t *testing.T) {
+	vr := New()
+	vr.Register("/home/:user/+")
+	vr.Register("/home/:user/.config")
+	m := vr.Compile()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i)
+			for j := 0; j < 1000; j++ {
+				matches, vars, matched := m.Match("/home/" + user + "/.config")
+				if !matched || len(matches) != 2 || vars["user"] != user {
+					t.Errorf("Concurrent Match failed: '%v', '%v'", matches, vars)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkMatchCompiled(b *testing.B) {
+	vl := New()
+	vl.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	m := vl.Compile()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	}
+}
+
+func BenchmarkMatchCompiled_Prefixes(b *testing.B) {
+	vr := New()
+	registerPrefixBench(vr, "/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	m := vr.Compile()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import "sync"
+
+// Param is a variable name and its value parsed from a path.
+type Param struct {
+	// Name is the variable name as defined in the matched template.
+	Name string
+	// Value is the variable value, a substring of the matched path.
+	Value string
+}
+
+// Params is a list of parsed variables in the order they were parsed.
+type Params []Param
+
+// Get returns the value of a variable by name and a truth if found.
+func (p Params) Get(name string) (value string, ok bool) {
+	for i := 0; i < len(p); i++ {
+		if p[i].Name == name {
+			return p[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// set sets the value of a variable by name, appending it if not found.
+func (p *Params) set(name, value string) {
+	for i := 0; i < len(*p); i++ {
+		if (*p)[i].Name == name {
+			(*p)[i].Value = value
+			return
+		}
+	}
+	*p = append(*p, Param{name, value})
+}
+
+// MatchContext holds results of MatchWith and can be reused between matches
+// to avoid allocations. Once its slices grow to accommodate matched templates
+// and parsed variables no further allocations are made.
+//
+// MatchContext is not safe for concurrent use.
+type MatchContext struct {
+	// Matches is a list of matched templates.
+	Matches []string
+	// Params is a list of parsed variables whose names and values are
+	// substrings of matched templates and path, respectively.
+	Params Params
+}
+
+// NewMatchContext returns a new *MatchContext with room for 8 matches and
+// 8 parameters.
+func NewMatchContext() *MatchContext {
+	return &MatchContext{
+		Matches: make([]string, 0, 8),
+		Params:  make(Params, 0, 8),
+	}
+}
+
+// Reset resets the context for reuse, retaining allocated space.
+func (mc *MatchContext) Reset() {
+	mc.Matches = mc.Matches[:0]
+	mc.Params = mc.Params[:0]
+}
+
+// contextPool is a pool of *MatchContext.
+var contextPool = sync.Pool{New: func() interface{} { return NewMatchContext() }}
+
+// AcquireMatchContext returns an empty *MatchContext from a pool.
+// It should be returned to the pool using ReleaseMatchContext once done with.
+func AcquireMatchContext() *MatchContext {
+	return contextPool.Get().(*MatchContext)
+}
+
+// ReleaseMatchContext resets mc and returns it to the pool. Results in mc
+// must not be used after it was released.
+func ReleaseMatchContext(mc *MatchContext) {
+	mc.Reset()
+	contextPool.Put(mc)
+}
+
+// MatchWith matches a path against registered templates and stores matched
+// templates and parsed variables to mc. Returns a boolean denoting if anything
+// was matched.
+//
+// Results are appended to mc which should be Reset before reuse. Templates are
+// matched and ordered as with Match.
+func (vr *Varouter) MatchWith(path string, mc *MatchContext) bool {
+	return vr.match(&path, &mc.Matches, nil, &mc.Params, false)
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchWith(t *testing.T) {
+	vr := New()
+	for _, template := range []string{
+		"/+",
+		"/home/:user",
+		"/home/:user/.config/:application",
+	} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mc := AcquireMatchContext()
+	defer ReleaseMatchContext(mc)
+	if !vr.MatchWith("/home/vedran/.config/myapp", mc) {
+		t.Fatal("MatchWith failed.")
+	}
+	if fmt.Sprint(mc.Matches) != "[/+ /home/:user/.config/:application]" {
+		t.Fatalf("MatchWith matches failed: '%v'", mc.Matches)
+	}
+	if fmt.Sprint(mc.Params) != "[{user vedran} {application myapp}]" {
+		t.Fatalf("MatchWith params failed: '%v'", mc.Params)
+	}
+	if val, ok := mc.Params.Get("application"); !ok || val != "myapp" {
+		t.Fatalf("Params.Get failed: '%s'", val)
+	}
+	mc.Reset()
+	if !vr.MatchWith("/home/vvuk", mc) {
+		t.Fatal("MatchWith failed.")
+	}
+	if len(mc.Params) != 1 || mc.Params[0].Value != "vvuk" {
+		t.Fatalf("MatchWith reset failed: '%v'", mc.Params)
+	}
+}
+
+func TestMatchWithAllocs(t *testing.T) {
+	vr := New()
+	vr.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	vr.Register("/home/:user/.config/:application")
+	mc := NewMatchContext()
+	for _, path := range []string{
+		"/home/users/vedran/Go/src/github.com/vedranvuk/varouter",
+		"/home/vedran/.config/myapp",
+	} {
+		if n := testing.AllocsPerRun(100, func() {
+			mc.Reset()
+			vr.MatchWith(path, mc)
+		}); n != 0 {
+			t.Fatalf("MatchWith allocated %v times for '%s'", n, path)
+		}
+	}
+}
+
+func BenchmarkMatchWith(b *testing.B) {
+	vr := New()
+	vr.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	mc := NewMatchContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc.Reset()
+		vr.MatchWith("/home/users/vedran/Go/src/github.com/vedranvuk/varouter", mc)
+	}
+}
+
+func BenchmarkMatchWith_Variables(b *testing.B) {
+	vr := New()
+	vr.Register("/home/:user/.config/:application")
+	mc := NewMatchContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc.Reset()
+		vr.MatchWith("/home/vedran/.config/myapp", mc)
+	}
+}
+
+func BenchmarkMatchWith_8ElemNumX8NamelenPool(b *testing.B) {
+	vr := New()
+	data := makeBenchData(b.N, 8, 8)
+	for _, template := range data.Templates {
+		vr.Register(template)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc := AcquireMatchContext()
+		vr.MatchWith(data.Paths[i], mc)
+		ReleaseMatchContext(mc)
+	}
+}
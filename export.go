@@ -0,0 +1,119 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotStyles maps node kinds to their Graphviz DOT node attributes.
+var dotStyles = map[NodeKind]string{
+	NodeLiteral:  "shape=box",
+	NodeVariable: "shape=ellipse",
+	NodeWildcard: "shape=hexagon",
+	NodePrefix:   "shape=box, style=dashed",
+}
+
+// WriteDOT writes the registered template tree to w as a Graphviz DOT graph.
+//
+// Literal nodes are drawn as boxes, variables as ellipses, wildcards as
+// hexagons and prefixes as dashed boxes. Nodes at which a template ends are
+// drawn with a double border and labeled with the template. Override nodes
+// are drawn red and exclusion nodes blue.
+//
+// Nodes are written in Walk order so the output is deterministic.
+func (vr *Varouter) WriteDOT(w io.Writer) error {
+	var bw = bufio.NewWriter(w)
+	var parents = []int{0}
+	var id int
+	bw.WriteString("digraph varouter {\n\tn0 [label=\"\", shape=point];\n")
+	if err := vr.Walk(func(node NodeInfo) error {
+		id++
+		parents = append(parents[:node.Depth+1], id)
+		var label = node.Name
+		var attrs = dotStyles[node.Kind]
+		if node.Terminal {
+			label += "\n" + node.Template
+			attrs += ", peripheries=2"
+		}
+		if node.Override {
+			attrs += ", color=red"
+		}
+		if node.Exclusion {
+			attrs += ", color=blue"
+		}
+		fmt.Fprintf(bw, "\tn%d [label=%s, %s];\n\tn%d -> n%d;\n",
+			id, dotQuote(label), attrs, parents[node.Depth], id)
+		return nil
+	}); err != nil {
+		return err
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// jsonNode is a template tree node written by WriteJSON.
+type jsonNode struct {
+	Name      string      `json:"name"`
+	Kind      NodeKind    `json:"kind"`
+	Path      string      `json:"path"`
+	Template  string      `json:"template,omitempty"`
+	Override  bool        `json:"override,omitempty"`
+	Exclusion bool        `json:"exclusion,omitempty"`
+	Priority  int         `json:"priority,omitempty"`
+	Nodes     []*jsonNode `json:"nodes,omitempty"`
+}
+
+// WriteJSON writes the registered template tree to w as an indented JSON
+// array of first level nodes. Each node is an object with "name", "kind"
+// and "path" keys, "template", "override", "exclusion" and "priority" keys
+// if set and a "nodes" key holding an array of its sub nodes, if any.
+//
+// Nodes are written in Walk order so the output is deterministic.
+func (vr *Varouter) WriteJSON(w io.Writer) error {
+	var root jsonNode
+	var parents = []*jsonNode{&root}
+	if err := vr.Walk(func(node NodeInfo) error {
+		var n = &jsonNode{
+			Name:      node.Name,
+			Kind:      node.Kind,
+			Path:      node.Path,
+			Template:  node.Template,
+			Override:  node.Override,
+			Exclusion: node.Exclusion,
+			Priority:  node.Priority,
+		}
+		var parent = parents[node.Depth]
+		parent.Nodes = append(parent.Nodes, n)
+		parents = append(parents[:node.Depth+1], n)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if root.Nodes == nil {
+		root.Nodes = []*jsonNode{}
+	}
+	var enc = json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root.Nodes)
+}
+
+// WriteDOT writes the compiled template tree as a Graphviz DOT graph.
+// See Varouter.WriteDOT for details.
+func (m *Matcher) WriteDOT(w io.Writer) error { return m.vr.WriteDOT(w) }
+
+// WriteJSON writes the compiled template tree as JSON.
+// See Varouter.WriteJSON for details.
+func (m *Matcher) WriteJSON(w io.Writer) error { return m.vr.WriteJSON(w) }
@@ -0,0 +1,125 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"bytes"
+	"testing"
+)
+
+var exportTestTemplates = []string{
+	"/+",
+	"!/home/:user",
+	"^/home/:user/\"x\"",
+	"/a/*.js",
+}
+
+func TestWriteDOT(t *testing.T) {
+	const expected = `digraph varouter {
+	n0 [label="", shape=point];
+	n1 [label="/\n/+", shape=box, style=dashed, peripheries=2];
+	n0 -> n1;
+	n2 [label="/a", shape=box];
+	n0 -> n2;
+	n3 [label="/*.js\n/a/*.js", shape=hexagon, peripheries=2];
+	n2 -> n3;
+	n4 [label="/home", shape=box];
+	n0 -> n4;
+	n5 [label="/:user\n!/home/:user", shape=ellipse, peripheries=2, color=red];
+	n4 -> n5;
+	n6 [label="/\"x\"\n^/home/:user/\"x\"", shape=box, peripheries=2, color=blue];
+	n5 -> n6;
+}
+`
+	var buf bytes.Buffer
+	if err := newExportTestVarouter(t, 0).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("WriteDOT failed:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := newExportTestVarouter(t, 3).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("WriteDOT is not deterministic:\n%s", buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	const expected = `[
+  {
+    "name": "/",
+    "kind": "prefix",
+    "path": "/",
+    "template": "/+"
+  },
+  {
+    "name": "/a",
+    "kind": "literal",
+    "path": "/a",
+    "nodes": [
+      {
+        "name": "/*.js",
+        "kind": "wildcard",
+        "path": "/a/*.js",
+        "template": "/a/*.js"
+      }
+    ]
+  },
+  {
+    "name": "/home",
+    "kind": "literal",
+    "path": "/home",
+    "nodes": [
+      {
+        "name": "/:user",
+        "kind": "variable",
+        "path": "/home/:user",
+        "template": "!/home/:user",
+        "override": true,
+        "nodes": [
+          {
+            "name": "/\"x\"",
+            "kind": "literal",
+            "path": "/home/:user/\"x\"",
+            "template": "^/home/:user/\"x\"",
+            "exclusion": true
+          }
+        ]
+      }
+    ]
+  }
+]
+`
+	var buf bytes.Buffer
+	if err := newExportTestVarouter(t, 0).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("WriteJSON failed:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := New().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatalf("WriteJSON of an empty tree failed:\n%s", buf.String())
+	}
+}
+
+// newExportTestVarouter returns a Varouter with exportTestTemplates
+// registered starting at offset.
+func newExportTestVarouter(t *testing.T, offset int) *Varouter {
+	vr := New()
+	for i := range exportTestTemplates {
+		template := exportTestTemplates[(i+offset)%len(exportTestTemplates)]
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vr
+}
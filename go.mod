@@ -1,5 +1,5 @@
 module github.com/vedranvuk/varouter
 
-go 1.15
+go 1.22
 
 require github.com/vedranvuk/randomex v0.2.0
@@ -0,0 +1,78 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+// prefixNode is a node of a trie of prefix elements keyed by bytes of their
+// names. A path element name is matched against all prefix elements whose
+// names it starts with by walking the trie along its bytes.
+type prefixNode struct {
+	// elem is the prefix element whose name ends at this node, if any.
+	elem *element
+	// up is the parent node, nil for the root node.
+	up *prefixNode
+	// key is the name byte leading to this node from the parent node.
+	key byte
+	// next are the child nodes sorted by key.
+	next []*prefixNode
+}
+
+// child returns the child node by key or nil if not found.
+func (n *prefixNode) child(key byte) *prefixNode {
+	var i, j, h = 0, len(n.next), 0
+	for i < j {
+		if h = int(uint(i+j) >> 1); n.next[h].key < key {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i < len(n.next) && n.next[i].key == key {
+		return n.next[i]
+	}
+	return nil
+}
+
+// insert inserts a prefix element into the trie of n by its name.
+func (n *prefixNode) insert(elem *element) {
+	var node, child *prefixNode
+	var i, j int
+	for node, i = n, 0; i < len(elem.name); i++ {
+		if child = node.child(elem.name[i]); child == nil {
+			child = &prefixNode{up: node, key: elem.name[i]}
+			for j = len(node.next); j > 0 && node.next[j-1].key > child.key; j-- {
+			}
+			node.next = append(node.next, nil)
+			copy(node.next[j+1:], node.next[j:])
+			node.next[j] = child
+		}
+		node = child
+	}
+	node.elem = elem
+}
+
+// last returns the deepest node of the trie of n along bytes of name.
+// Nodes from the returned node up to n include all nodes with prefix elements
+// whose names name starts with.
+func (n *prefixNode) last(name *string) *prefixNode {
+	var node, child = n, n
+	for i := 0; i < len(*name); i++ {
+		if child = node.child((*name)[i]); child == nil {
+			break
+		}
+		node = child
+	}
+	return node
+}
+
+// insertWildcard inserts a wildcard element into wildcards sorted by name.
+func insertWildcard(wildcards []*element, elem *element) []*element {
+	var i = len(wildcards)
+	for ; i > 0 && wildcards[i-1].name > elem.name; i-- {
+	}
+	wildcards = append(wildcards, nil)
+	copy(wildcards[i+1:], wildcards[i:])
+	wildcards[i] = elem
+	return wildcards
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrefixIndex(t *testing.T) {
+	vr := New()
+	for _, template := range []string{
+		"/ab+",
+		"/+",
+		"/b+",
+		"/abc+",
+		"/a+",
+		"/abd",
+	} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	node := vr.root.prefixes.child('/')
+	if node == nil || node.elem == nil || node.elem.name != "/" {
+		t.Fatal("Prefix trie failed.")
+	}
+	var keys []byte
+	for _, next := range node.next {
+		keys = append(keys, next.key)
+	}
+	if string(keys) != "ab" {
+		t.Fatalf("Prefix trie failed: '%s'", keys)
+	}
+	matches, _, matched := vr.Match("/abcd")
+	if !matched || fmt.Sprint(matches) != "[/+ /a+ /ab+ /abc+]" {
+		t.Fatalf("Prefix match failed: '%v'", matches)
+	}
+	if template, _, matched := vr.MatchOne("/abcd"); !matched || template != "/abc+" {
+		t.Fatalf("Prefix MatchOne failed: '%s'", template)
+	}
+}
+
+func TestWildcardIndex(t *testing.T) {
+	vr := New()
+	for _, template := range []string{"/c*", "/a*", "/?", "/b*"} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for _, elem := range vr.root.wildcards {
+		names = append(names, elem.name)
+	}
+	if fmt.Sprint(names) != "[/? /a* /b* /c*]" {
+		t.Fatalf("Wildcard index failed: '%v'", names)
+	}
+}
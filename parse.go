@@ -0,0 +1,189 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import "fmt"
+
+// ErrParse is returned when a template cannot be parsed.
+var ErrParse = fmt.Errorf("%w: parse", ErrRegister)
+
+// ParseError is returned by Parse and Register when a template is invalid.
+type ParseError struct {
+	// Template is the invalid template.
+	Template string
+	// Pos is the byte offset in Template at which the error was detected.
+	Pos int
+	// Err is the error.
+	Err error
+}
+
+// Error implements error.
+func (pe *ParseError) Error() string {
+	return fmt.Sprintf("%v: '%s' at position %d", pe.Err, pe.Template, pe.Pos)
+}
+
+// Unwrap returns the error.
+func (pe *ParseError) Unwrap() error { return pe.Err }
+
+// Tokens are the characters templates are parsed with.
+type Tokens struct {
+	Override     byte // Override is the override character.
+	Exclusion    byte // Exclusion is the exclusion character.
+	Separator    byte // Separator is the path separator character.
+	Variable     byte // Variable is the variable placeholder character.
+	Prefix       byte // Prefix is the prefix character.
+	WildcardOne  byte // WildcardOne is the character that matches any one character.
+	WildcardMany byte // WildcardMany is the character that matches one or more characters.
+}
+
+// DefaultTokens are the tokens of a Varouter returned by New.
+var DefaultTokens = Tokens{'!', '^', '/', ':', '+', '?', '*'}
+
+// Tokens returns the tokens vr parses templates with.
+func (vr *Varouter) Tokens() Tokens {
+	return Tokens{vr.override, vr.exclusion, vr.separator, vr.variable,
+		vr.prefix, vr.wildcardone, vr.wildcardmany}
+}
+
+// SegmentKind is the kind of a parsed template segment.
+type SegmentKind int
+
+const (
+	// SegmentLiteral is a path element matched exactly.
+	SegmentLiteral SegmentKind = iota
+	// SegmentVariable is a path element that matches any name as the value
+	// of a variable.
+	SegmentVariable
+	// SegmentWildcard is a path element matched as a wildcard pattern.
+	SegmentWildcard
+	// SegmentPrefix is the prefix marker that ends a prefix template.
+	SegmentPrefix
+)
+
+// String implements fmt.Stringer.
+func (k SegmentKind) String() string {
+	switch k {
+	case SegmentLiteral:
+		return "literal"
+	case SegmentVariable:
+		return "variable"
+	case SegmentWildcard:
+		return "wildcard"
+	case SegmentPrefix:
+		return "prefix"
+	}
+	return "unknown"
+}
+
+// Segment is a parsed template segment.
+type Segment struct {
+	// Kind is the segment kind.
+	Kind SegmentKind
+	// Pos is the byte offset of the segment in the template.
+	Pos int
+	// Text is the segment text as it appears in the template. Path element
+	// segments include the leading separator, i.e. "/home", "/:user".
+	Text string
+	// Name is the variable name of a variable segment, the text following
+	// the separator of a literal or wildcard segment and empty for a prefix
+	// segment, i.e. "home", "user".
+	Name string
+}
+
+// Template is a parsed template.
+type Template struct {
+	// Text is the parsed template.
+	Text string
+	// Override specifies if the template is an override.
+	Override bool
+	// Exclusion specifies if the template is an exclusion.
+	Exclusion bool
+	// Prefix specifies if the template is a prefix template.
+	Prefix bool
+	// Segments are the path element segments of the template in order,
+	// followed by a prefix segment if Prefix.
+	Segments []Segment
+}
+
+// Parse parses a template using the specified tokens and returns it as a
+// *Template or a *ParseError if the template is invalid.
+//
+// Parse validates the template itself but not its compatibility with other
+// templates. See Register for template syntax.
+func Parse(template string, tokens Tokens) (*Template, error) {
+	var fail = func(pos int, format string) (*Template, error) {
+		return nil, &ParseError{template, pos, fmt.Errorf("%w: "+format, ErrParse)}
+	}
+	var length = len(template)
+	if length < 1 {
+		return fail(0, "empty template")
+	}
+	var t = &Template{Text: template}
+	for i := 0; i < length; i++ {
+		if template[i] == tokens.Prefix && i < length-1 {
+			return fail(i, "prefix character allowed only as suffix")
+		}
+	}
+	var cursor int
+	switch template[0] {
+	case tokens.Override:
+		t.Override = true
+		cursor++
+	case tokens.Exclusion:
+		t.Exclusion = true
+		cursor++
+	}
+	if cursor >= length || template[cursor] != tokens.Separator {
+		return fail(cursor, "template must start with a separator")
+	}
+	if template[length-1] == tokens.Prefix {
+		t.Prefix = true
+		length--
+	}
+	var marker int
+	for marker = cursor; marker < length; marker = cursor {
+		for cursor = marker + 1; cursor < length && template[cursor] != tokens.Separator; cursor++ {
+		}
+		var segment = Segment{
+			Kind: SegmentLiteral,
+			Pos:  marker,
+			Text: template[marker:cursor],
+			Name: template[marker+1 : cursor],
+		}
+		var wildcard = -1
+		for i := marker + 1; i < cursor && wildcard < 0; i++ {
+			if template[i] == tokens.WildcardOne || template[i] == tokens.WildcardMany {
+				wildcard = i
+			}
+		}
+		if wildcard >= 0 {
+			segment.Kind = SegmentWildcard
+		}
+		if segment.Name != "" && segment.Name[0] == tokens.Variable {
+			if len(segment.Name) < 2 {
+				return fail(marker, "empty variable name")
+			}
+			for i := marker + 2; i < cursor; i++ {
+				if template[i] == tokens.Variable {
+					return fail(i, "invalid variable name")
+				}
+			}
+			if wildcard >= 0 {
+				return fail(wildcard, "variable names cannot contain wildcards")
+			}
+			segment.Kind = SegmentVariable
+			segment.Name = segment.Name[1:]
+		}
+		t.Segments = append(t.Segments, segment)
+	}
+	if t.Prefix {
+		t.Segments = append(t.Segments, Segment{
+			Kind: SegmentPrefix,
+			Pos:  length,
+			Text: template[length:],
+		})
+	}
+	return t, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// segmentsString returns a string representation of parsed segments.
+func segmentsString(t *Template) string {
+	var a []string
+	for _, segment := range t.Segments {
+		a = append(a, fmt.Sprintf("%s@%d:%q:%q", segment.Kind, segment.Pos, segment.Text, segment.Name))
+	}
+	return strings.Join(a, " ")
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		template  string
+		segments  string
+		override  bool
+		exclusion bool
+		prefix    bool
+	}{
+		{"/", `literal@0:"/":""`, false, false, false},
+		{"/+", `literal@0:"/":"" prefix@1:"+":""`, false, false, true},
+		{"/home//", `literal@0:"/home":"home" literal@5:"/":"" literal@6:"/":""`, false, false, false},
+		{"/home/:user", `literal@0:"/home":"home" variable@5:"/:user":"user"`, false, false, false},
+		{"!/static/*.js", `literal@1:"/static":"static" wildcard@8:"/*.js":"*.js"`, true, false, false},
+		{"^/api/internal/+", `literal@1:"/api":"api" literal@5:"/internal":"internal" literal@14:"/":"" prefix@15:"+":""`, false, true, true},
+		{"/a:b/c?+", `literal@0:"/a:b":"a:b" wildcard@4:"/c?":"c?" prefix@7:"+":""`, false, false, true},
+	} {
+		tmpl, err := Parse(test.template, DefaultTokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := segmentsString(tmpl); s != test.segments {
+			t.Fatalf("Parse(%q) segments failed: %s", test.template, s)
+		}
+		if tmpl.Text != test.template || tmpl.Override != test.override ||
+			tmpl.Exclusion != test.exclusion || tmpl.Prefix != test.prefix {
+			t.Fatalf("Parse(%q) failed: '%+v'", test.template, tmpl)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		pos      int
+	}{
+		{"", 0},
+		{"no", 0},
+		{"!", 1},
+		{"!no", 1},
+		{"/++", 1},
+		{"/a+/b", 2},
+		{"/:", 0},
+		{"/home/:/", 5},
+		{"/home/:no:", 9},
+		{"/home/:no*+", 9},
+	} {
+		_, err := Parse(test.template, DefaultTokens)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Pos != test.pos || pe.Template != test.template {
+			t.Fatalf("Parse(%q) returned '%v', expected error at %d", test.template, err, test.pos)
+		}
+		if !errors.Is(err, ErrParse) || !errors.Is(err, ErrRegister) {
+			t.Fatalf("Parse(%q) returned an unexpected error: '%v'", test.template, err)
+		}
+		if New().Register(test.template) == nil {
+			t.Fatalf("Register(%q) did not fail.", test.template)
+		}
+	}
+}
+
+func TestRegisterAtomic(t *testing.T) {
+	vr := New()
+	if err := vr.Register("/a/:b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := vr.Register("/a/c/d/e"); err == nil {
+		t.Fatal("Failed detecting element registration on a level with a variable.")
+	}
+	if err := vr.Register("/a/:b/d"); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	vr.Walk(func(node NodeInfo) error {
+		paths = append(paths, node.Path)
+		return nil
+	})
+	if fmt.Sprint(paths) != "[/a /a/:b /a/:b/d]" {
+		t.Fatalf("Failed registration modified the tree: '%v'", paths)
+	}
+}
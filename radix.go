@@ -0,0 +1,145 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+// Backend specifies the structure Varouter matches paths against.
+type Backend int
+
+const (
+	// BackendMap matches paths against a tree of maps, one map per
+	// registered template level. It suits wide template trees and is the
+	// default backend.
+	BackendMap Backend = iota
+	// BackendRadix matches paths against a compressed radix tree in which
+	// chains of literal template levels with a single sub level are merged
+	// into a single level. It suits deep and narrow template trees, such as
+	// long static paths.
+	BackendRadix
+	// BackendAuto selects BackendRadix at Freeze if at least half of the
+	// registered template levels can be merged, BackendMap otherwise.
+	BackendAuto
+)
+
+// SetBackend selects the matching backend. The selected backend is built and
+// used by Match after the next call to Freeze.
+func (vr *Varouter) SetBackend(backend Backend) { vr.backend = backend }
+
+// Freeze builds the structure of the selected backend from registered
+// templates.
+//
+// Registered templates are matched using BackendMap until Freeze is called.
+// Registering a template after Freeze reverts the structure to BackendMap
+// until Freeze is called again.
+//
+// Freeze is not safe for concurrent use with Register or any Match method.
+func (vr *Varouter) Freeze() {
+	if vr.compressed {
+		vr.expand(vr.root)
+		vr.compressed = false
+	}
+	switch vr.backend {
+	case BackendRadix:
+		vr.compressed = true
+	case BackendAuto:
+		var merged, total = countMergeable(vr.root)
+		vr.compressed = total > 0 && merged*2 >= total
+	}
+	if vr.compressed {
+		compress(vr.root)
+	}
+}
+
+// mergeable returns if e has a single literal sub element that can be merged
+// into e.
+func mergeable(e *element) bool {
+	return len(e.subs) == 1 && e.template == "" && !e.isprefix && !e.iswildcard &&
+		e.hasvariable == "" && !e.hasprefixes && !e.haswildcards
+}
+
+// countMergeable returns the number of elements in the tree of e that can be
+// merged into their parent by compress and the total number of elements.
+func countMergeable(e *element) (merged, total int) {
+	var m, t int
+	for _, sub := range e.subs {
+		if mergeable(sub) {
+			merged++
+		}
+		m, t = countMergeable(sub)
+		merged += m
+		total += t + 1
+	}
+	return
+}
+
+// compress compresses the tree of e in place by merging single literal sub
+// elements into their parents, bottom up. The tail of a merged element holds
+// the names of elements merged into it. Root e is never merged.
+func compress(e *element) {
+	var name string
+	var sub, only *element
+	for _, sub = range e.subs {
+		compress(sub)
+		if !mergeable(sub) {
+			continue
+		}
+		// Sub elements are compressed so the one being merged is not
+		// mergeable itself and merging is done once per element.
+		for name, only = range sub.subs {
+			var tail = sub.tail + name + only.tail
+			name = sub.name
+			*sub = *only
+			sub.name = name
+			sub.tail = tail
+		}
+	}
+}
+
+// expand reverts compress by splitting element tails back into elements.
+func (vr *Varouter) expand(e *element) {
+	var name string
+	var sub, head, elem *element
+	var i, marker int
+	for name, sub = range e.subs {
+		vr.expand(sub)
+		if sub.tail == "" {
+			continue
+		}
+		// Rebuild the chain of names in tail, sub becomes its last element.
+		head = newElement()
+		head.name = name
+		elem = head
+		for i, marker = 1, 0; i < len(sub.tail); i++ {
+			if sub.tail[i] != vr.separator {
+				continue
+			}
+			elem.subs[sub.tail[marker:i]] = newElement()
+			elem = elem.subs[sub.tail[marker:i]]
+			elem.name = sub.tail[marker:i]
+			marker = i
+		}
+		sub.name = sub.tail[marker:]
+		elem.subs[sub.name] = sub
+		sub.tail = ""
+		e.subs[name] = head
+	}
+}
+
+// matchTail advances cursor past the tail of state.current if it follows
+// cursor in state.path. Returns false if the tail does not follow.
+func (vr *Varouter) matchTail(cursor *int, state *matchState) bool {
+	var tail = state.current.tail
+	if tail == "" {
+		return true
+	}
+	var end = *cursor + len(tail)
+	if end > state.length || state.path[*cursor:end] != tail {
+		return false
+	}
+	if end < state.length && state.path[end] != vr.separator {
+		return false
+	}
+	*cursor = end
+	return true
+}
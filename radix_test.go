@@ -0,0 +1,77 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestRadixRegisterAfterFreeze(t *testing.T) {
+	vr := New()
+	vr.SetBackend(BackendRadix)
+	for _, template := range []string{
+		"/home/users/vedran/.config",
+		"/home//users/:user/.config/app",
+	} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vr.Freeze()
+	if !vr.compressed || vr.root.subs["/home"].subs["/users"].tail != "/vedran/.config" {
+		t.Fatal("Freeze failed to compress.")
+	}
+	if err := vr.Register("/home/users/vedran/.cache"); err != nil {
+		t.Fatal(err)
+	}
+	if vr.compressed {
+		t.Fatal("Register failed to expand.")
+	}
+	templates := vr.DefinedTemplates()
+	sort.Strings(templates)
+	if fmt.Sprint(templates) != "[/home//users/:user/.config/app /home/users/vedran/.cache /home/users/vedran/.config]" {
+		t.Fatalf("Expand failed: '%v'", templates)
+	}
+	vr.Freeze()
+	for _, path := range []string{
+		"/home/users/vedran/.config",
+		"/home/users/vedran/.cache",
+		"/home//users/vedran/.config/app",
+	} {
+		if _, _, matched := vr.Match(path); !matched {
+			t.Fatalf("Match failed: '%s'", path)
+		}
+	}
+	for _, path := range []string{
+		"/home/users/vedran",
+		"/home/users/vedran/.configs",
+		"/home/users/vedran/.config/app",
+	} {
+		if _, _, matched := vr.Match(path); matched {
+			t.Fatalf("Match false positive: '%s'", path)
+		}
+	}
+}
+
+func TestRadixAuto(t *testing.T) {
+	vr := New()
+	vr.SetBackend(BackendAuto)
+	vr.Register("/a/b/c/d")
+	vr.Freeze()
+	if !vr.compressed {
+		t.Fatal("Auto backend failed to select radix.")
+	}
+	vr = New()
+	vr.SetBackend(BackendAuto)
+	vr.Register("/a/b")
+	vr.Register("/a/c")
+	vr.Register("/a/d")
+	vr.Freeze()
+	if vr.compressed {
+		t.Fatal("Auto backend failed to select map.")
+	}
+}
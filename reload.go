@@ -0,0 +1,185 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader serves a *Varouter with routes loaded from a file using LoadRoutes
+// and replaces it with a newly loaded *Varouter when the file changes.
+//
+// The file is polled for changes using os.Stat. A changed file is loaded into
+// a new *Varouter in the background which, if loaded and validated without
+// errors, atomically replaces the served *Varouter. If a reload fails the
+// served *Varouter is retained and the error is passed to OnError.
+//
+// Exported fields must be set before Start and not modified afterwards.
+type Reloader struct {
+	// New, if not nil, returns a new *Varouter routes are loaded into.
+	// It can be used to configure tokens, backend or cache. Default: New.
+	New func() *Varouter
+	// Validate, if not nil, is called with a loaded *Varouter before it is
+	// served. If it returns an error the reload fails.
+	Validate func(vr *Varouter) error
+	// OnError, if not nil, is called with the error of a failed reload.
+	OnError func(err error)
+
+	path     string        // path is the route file path.
+	interval time.Duration // interval is the polling interval.
+	current  atomic.Value  // current holds the served *Varouter.
+
+	mu      sync.Mutex    // mu serializes reloads.
+	modtime time.Time     // modtime is the modification time of the last loaded file.
+	size    int64         // size is the size of the last loaded file.
+	staterr bool          // staterr denotes the last file stat failed.
+	stop    chan struct{} // stop, if not nil, stops polling when closed.
+	done    chan struct{} // done is closed when polling stops.
+}
+
+// NewReloader returns a new *Reloader of routes defined in the file at path
+// which is polled for changes at the specified interval.
+func NewReloader(path string, interval time.Duration) *Reloader {
+	return &Reloader{
+		path:     path,
+		interval: interval,
+	}
+}
+
+// Start loads the route file and starts polling it for changes. If the file
+// cannot be loaded the error is returned and polling is not started.
+//
+// Start must not be called again before Stop.
+func (rl *Reloader) Start() error {
+	if err := rl.Reload(); err != nil {
+		return err
+	}
+	rl.stop = make(chan struct{})
+	rl.done = make(chan struct{})
+	go rl.poll(rl.stop, rl.done)
+	return nil
+}
+
+// Stop stops polling the route file and waits for a reload in progress to
+// complete. The last loaded *Varouter continues to be served.
+func (rl *Reloader) Stop() {
+	if rl.stop == nil {
+		return
+	}
+	close(rl.stop)
+	<-rl.done
+	rl.stop = nil
+}
+
+// Varouter returns the served *Varouter or nil if none was loaded.
+//
+// The returned *Varouter is shared and may be used for matching concurrently
+// but must not be modified.
+func (rl *Reloader) Varouter() *Varouter {
+	if vr, ok := rl.current.Load().(*Varouter); ok {
+		return vr
+	}
+	return nil
+}
+
+// Reload loads the route file into a new *Varouter and, if it was loaded and
+// validated without errors, serves it. Otherwise the error is returned and
+// the served *Varouter is retained. Reload does not call OnError.
+func (rl *Reloader) Reload() error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	var info, err = os.Stat(rl.path)
+	if err != nil {
+		return err
+	}
+	return rl.load(info)
+}
+
+// poll polls the route file for changes until stop is closed, then closes
+// done.
+func (rl *Reloader) poll(stop, done chan struct{}) {
+	defer close(done)
+	var ticker = time.NewTicker(rl.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rl.check()
+		}
+	}
+}
+
+// check reloads the route file if it changed since it was last loaded and
+// passes any error to OnError. A failed file version is not reloaded until it
+// changes again.
+func (rl *Reloader) check() {
+	// OnError is called without holding the lock so that it may call Reload.
+	if err := rl.reloadChanged(); err != nil {
+		rl.fail(err)
+	}
+}
+
+// reloadChanged reloads the route file if it changed since it was last
+// loaded and returns an error to report, if any.
+func (rl *Reloader) reloadChanged() error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	var info, err = os.Stat(rl.path)
+	if err != nil {
+		// Report stat errors once until the file is back.
+		if rl.staterr {
+			return nil
+		}
+		rl.staterr = true
+		return err
+	}
+	rl.staterr = false
+	if info.ModTime().Equal(rl.modtime) && info.Size() == rl.size {
+		return nil
+	}
+	return rl.load(info)
+}
+
+// load loads the route file described by info and serves it if loaded and
+// validated without errors.
+func (rl *Reloader) load(info os.FileInfo) (err error) {
+	rl.modtime = info.ModTime()
+	rl.size = info.Size()
+	var file *os.File
+	if file, err = os.Open(rl.path); err != nil {
+		return err
+	}
+	defer file.Close()
+	var vr *Varouter
+	if rl.New != nil {
+		vr = rl.New()
+	} else {
+		vr = New()
+	}
+	if _, err = vr.LoadRoutes(file); err != nil {
+		return fmt.Errorf("%s: %w", rl.path, err)
+	}
+	if rl.Validate != nil {
+		if err = rl.Validate(vr); err != nil {
+			return fmt.Errorf("%s: %w", rl.path, err)
+		}
+	}
+	vr.Freeze()
+	rl.current.Store(vr)
+	return nil
+}
+
+// fail passes err to OnError if set.
+func (rl *Reloader) fail(err error) {
+	if rl.OnError != nil {
+		rl.OnError(err)
+	}
+}
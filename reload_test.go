@@ -0,0 +1,114 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRouteFile(t *testing.T, path, data string, modtime time.Time) {
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modtime, modtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes")
+	now := time.Now()
+	writeRouteFile(t, path, "/home/:user\n", now)
+	errs := make(chan error, 1)
+	rl := NewReloader(path, 5*time.Millisecond)
+	rl.OnError = func(err error) { errs <- err }
+	if err := rl.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Stop()
+	first := rl.Varouter()
+	if template, _, _ := first.MatchTop("/home/vedran"); template != "/home/:user" {
+		t.Fatalf("Reloader initial load failed: '%s'", template)
+	}
+
+	writeRouteFile(t, path, "/home/:user\n/static/+\n", now.Add(time.Second))
+	deadline := time.Now().Add(5 * time.Second)
+	for rl.Varouter() == first {
+		if time.Now().After(deadline) {
+			t.Fatal("Reloader did not reload a changed file.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	second := rl.Varouter()
+	if template, _, _ := second.MatchTop("/static/app.js"); template != "/static/+" {
+		t.Fatalf("Reloader reload failed: '%s'", template)
+	}
+
+	writeRouteFile(t, path, "/home/:user\n/home/:admin\n", now.Add(2*time.Second))
+	select {
+	case err := <-errs:
+		var le *LoadError
+		if !errors.As(err, &le) || le.Line != 2 {
+			t.Fatalf("Reloader reported an unexpected error: '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reloader did not report a failed reload.")
+	}
+	if rl.Varouter() != second {
+		t.Fatal("Reloader did not retain the served Varouter after a failed reload.")
+	}
+}
+
+func TestReloaderValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes")
+	writeRouteFile(t, path, "/home\n", time.Now())
+	errInvalid := errors.New("invalid")
+	rl := NewReloader(path, time.Second)
+	rl.Validate = func(vr *Varouter) error {
+		if _, _, matched := vr.Match("/home"); matched {
+			return errInvalid
+		}
+		return nil
+	}
+	if err := rl.Start(); !errors.Is(err, errInvalid) {
+		t.Fatalf("Reloader validation failed: '%v'", err)
+	}
+	if rl.Varouter() != nil {
+		t.Fatal("Reloader served an invalid Varouter.")
+	}
+	rl.Stop()
+}
+
+func TestReloaderOnErrorReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes")
+	now := time.Now()
+	writeRouteFile(t, path, "/home\n", now)
+	errs := make(chan error, 1)
+	rl := NewReloader(path, 5*time.Millisecond)
+	rl.OnError = func(err error) {
+		// Retrying from OnError must not deadlock.
+		select {
+		case errs <- rl.Reload():
+		default:
+		}
+	}
+	if err := rl.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Stop()
+	writeRouteFile(t, path, "/home/:user\n/home/:admin\n", now.Add(time.Second))
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("Reload from OnError loaded an invalid file.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload from OnError deadlocked.")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// ErrLoad is returned when route definitions cannot be loaded.
+var ErrLoad = fmt.Errorf("%w: load", ErrVarouter)
+
+// Route is a route definition registered by RegisterRoute or LoadRoutes.
+type Route struct {
+	// Template is the registered template.
+	Template string `json:"template"`
+	// Name, if not empty, is a unique route name.
+	Name string `json:"name,omitempty"`
+	// Priority is the template priority. See RegisterPriority.
+	Priority int `json:"priority,omitempty"`
+	// Meta is arbitrary route metadata.
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// LoadError is returned by LoadRoutes when a route definition on a line of
+// the input cannot be parsed or registered.
+type LoadError struct {
+	// Line is the input line number, starting at 1.
+	Line int
+	// Err is the parse or registration error.
+	Err error
+}
+
+// Error implements error.
+func (le *LoadError) Error() string { return fmt.Sprintf("line %d: %v", le.Line, le.Err) }
+
+// Unwrap returns the parse or registration error.
+func (le *LoadError) Unwrap() error { return le.Err }
+
+// RegisterRoute registers route template with route priority and stores route
+// name and metadata which can be retrieved using Route and RouteByName.
+//
+// If route name is not empty it must be unique among registered routes.
+func (vr *Varouter) RegisterRoute(route Route) error {
+	if _, exists := vr.names[route.Name]; route.Name != "" && exists {
+		return fmt.Errorf("%w: duplicate route name '%s'", ErrRegister, route.Name)
+	}
+	if err := vr.RegisterPriority(route.Template, route.Priority); err != nil {
+		return err
+	}
+	if vr.routes == nil {
+		vr.routes = make(map[string]*Route)
+		vr.names = make(map[string]string)
+	}
+	route.Meta = maps.Clone(route.Meta)
+	vr.routes[route.Template] = &route
+	if route.Name != "" {
+		vr.names[route.Name] = route.Template
+	}
+	return nil
+}
+
+// Route returns a copy of the route registered with RegisterRoute or
+// LoadRoutes by template and a truth if found.
+func (vr *Varouter) Route(template string) (route Route, ok bool) {
+	var r *Route
+	if r, ok = vr.routes[template]; ok {
+		route = *r
+		route.Meta = maps.Clone(r.Meta)
+	}
+	return
+}
+
+// RouteByName returns a copy of the route registered with RegisterRoute or
+// LoadRoutes by name and a truth if found.
+func (vr *Varouter) RouteByName(name string) (route Route, ok bool) {
+	var template string
+	if template, ok = vr.names[name]; ok {
+		return vr.Route(template)
+	}
+	return
+}
+
+// LoadRoutes reads route definitions from r and registers them in order using
+// RegisterRoute. It returns the registered routes.
+//
+// Input whose first non-space character is '[' is decoded as a JSON array of
+// Route objects, for example:
+//
+//	[
+//	  {"template": "/home/:user", "name": "user", "priority": 1},
+//	  {"template": "/static/+", "meta": {"cache": "max-age=3600"}}
+//	]
+//
+// Otherwise input is read as text with one route per line. A line consists of
+// a template followed by optional whitespace separated key=value fields. The
+// "name" and "priority" keys set route name and priority and all other keys are
+// stored as metadata. Empty lines and text from a '#' character that starts a
+// field to the end of a line are ignored. For example:
+//
+//	# Users.
+//	/home/:user    name=user priority=1
+//	/static/+      cache=max-age=3600 # Static files.
+//
+// If a route cannot be parsed or registered a *LoadError holding the input
+// line number is returned. Routes registered before the error remain
+// registered.
+func (vr *Varouter) LoadRoutes(r io.Reader) (routes []Route, err error) {
+	var data []byte
+	if data, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return vr.loadJSON(data)
+	}
+	return vr.loadText(data)
+}
+
+// loadJSON registers routes from JSON data.
+func (vr *Varouter) loadJSON(data []byte) (routes []Route, err error) {
+	var dec = json.NewDecoder(bytes.NewReader(data))
+	var fail = func(offset int64, err error) ([]Route, error) {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			offset = se.Offset
+		}
+		return routes, &LoadError{lineAt(data, offset), fmt.Errorf("%w: %v", ErrLoad, err)}
+	}
+	if _, err = dec.Token(); err != nil {
+		return fail(dec.InputOffset(), err)
+	}
+	var offset int64
+	for dec.More() {
+		offset = valueOffset(data, dec.InputOffset())
+		var route Route
+		if err = dec.Decode(&route); err != nil {
+			return fail(offset, err)
+		}
+		if err = vr.RegisterRoute(route); err != nil {
+			return routes, &LoadError{lineAt(data, offset), err}
+		}
+		routes = append(routes, route)
+	}
+	if _, err = dec.Token(); err != nil {
+		return fail(dec.InputOffset(), err)
+	}
+	return routes, nil
+}
+
+// loadText registers routes from line-oriented text data.
+func (vr *Varouter) loadText(data []byte) (routes []Route, err error) {
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	var line int
+	var route Route
+	for scanner.Scan() {
+		line++
+		if route, err = parseRouteLine(scanner.Text()); err != nil {
+			return routes, &LoadError{line, err}
+		}
+		if route.Template == "" {
+			continue
+		}
+		if err = vr.RegisterRoute(route); err != nil {
+			return routes, &LoadError{line, err}
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseRouteLine parses a text route definition line. It returns an empty
+// route if the line defines none.
+func parseRouteLine(line string) (route Route, err error) {
+	var fields = strings.Fields(line)
+	for i, field := range fields {
+		if field[0] == '#' {
+			break
+		}
+		if i == 0 {
+			route.Template = field
+			continue
+		}
+		var eq = strings.IndexByte(field, '=')
+		if eq < 1 {
+			return Route{}, fmt.Errorf("%w: invalid field '%s'", ErrLoad, field)
+		}
+		var key, value = field[:eq], field[eq+1:]
+		switch key {
+		case "name":
+			route.Name = value
+		case "priority":
+			if route.Priority, err = strconv.Atoi(value); err != nil {
+				return Route{}, fmt.Errorf("%w: invalid priority '%s'", ErrLoad, value)
+			}
+		default:
+			if route.Meta == nil {
+				route.Meta = make(map[string]string)
+			}
+			route.Meta[key] = value
+		}
+	}
+	return
+}
+
+// valueOffset returns the offset of the next JSON value in data from offset,
+// skipping whitespace and separators.
+func valueOffset(data []byte, offset int64) int64 {
+	for ; offset < int64(len(data)); offset++ {
+		if c := data[offset]; c != ' ' && c != '\t' && c != '\r' && c != '\n' && c != ',' {
+			break
+		}
+	}
+	return offset
+}
+
+// lineAt returns the line number of offset in data, starting at 1.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte{'\n'}) + 1
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadRoutesText(t *testing.T) {
+	vr := New()
+	routes, err := vr.LoadRoutes(strings.NewReader(`
+# Users.
+/home/:user    name=user priority=1
+/static/+      cache=max-age=3600 # Static files.
+
+/+
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 3 {
+		t.Fatalf("LoadRoutes failed: '%v'", routes)
+	}
+	route, ok := vr.RouteByName("user")
+	if !ok || route.Template != "/home/:user" || route.Priority != 1 {
+		t.Fatalf("RouteByName failed: '%v'", route)
+	}
+	route, ok = vr.Route("/static/+")
+	if !ok || route.Meta["cache"] != "max-age=3600" {
+		t.Fatalf("Route failed: '%v'", route)
+	}
+	route.Meta["cache"] = "no-cache"
+	if route, _ = vr.Route("/static/+"); route.Meta["cache"] != "max-age=3600" {
+		t.Fatal("Route returned metadata shared with the router.")
+	}
+	if template, _, _ := vr.MatchTop("/static/app.js"); template != "/static/+" {
+		t.Fatalf("Match loaded route failed: '%s'", template)
+	}
+}
+
+func TestLoadRoutesJSON(t *testing.T) {
+	vr := New()
+	routes, err := vr.LoadRoutes(strings.NewReader(`[
+	{"template": "/home/:user", "name": "user", "priority": 1},
+	{"template": "/static/+", "meta": {"cache": "max-age=3600"}}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("LoadRoutes failed: '%v'", routes)
+	}
+	if route, ok := vr.RouteByName("user"); !ok || route.Priority != 1 {
+		t.Fatalf("RouteByName failed: '%v'", route)
+	}
+	if route, ok := vr.Route("/static/+"); !ok || route.Meta["cache"] != "max-age=3600" {
+		t.Fatalf("Route failed: '%v'", route)
+	}
+}
+
+func TestLoadRoutesErrors(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		line  int
+		err   error
+	}{
+		{"/home\n\n/home\n", 3, ErrDuplicate},
+		{"/home\n/users name=a\n/admin name=a\n", 3, ErrRegister},
+		{"/home priority=high\n", 1, ErrLoad},
+		{"/home\n/users invalid\n", 2, ErrLoad},
+		{"home\n", 1, ErrRegister},
+		{"[\n\t{\"template\": \"/home\"},\n\t{\"template\": \"/home\"}\n]", 3, ErrDuplicate},
+		{"[\n\t{\"template\": \"/home\"},\n\t{\"template\": 1}\n]", 3, ErrLoad},
+		{"[\n\t{\"template\": \"/home\"},\n\t{\"template\" \"/users\"}\n]", 3, ErrLoad},
+	} {
+		_, err := New().LoadRoutes(strings.NewReader(test.input))
+		var le *LoadError
+		if !errors.As(err, &le) {
+			t.Fatalf("LoadRoutes(%q) returned '%v', expected a *LoadError", test.input, err)
+		}
+		if le.Line != test.line || !errors.Is(err, test.err) {
+			t.Fatalf("LoadRoutes(%q) returned '%v', expected line %d and '%v'", test.input, err, test.line, test.err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// layerKey is the context key of the layer serving a request.
+var layerKey = placeholderKey{"varouter/servemux/layer"}
+
+// layer is a handler of a prefix template wrapping a handler of a template
+// it matches in ModeCascade.
+type layer struct {
+	// handler is the handler of the prefix template.
+	handler http.Handler
+	// inner is the wrapped handler.
+	inner http.Handler
+}
+
+// ServeHTTP implements http.Handler.
+func (l *layer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), layerKey, l)))
+}
+
+// Cascade serves r by the handler wrapped by the handler serving r in
+// ModeCascade. A handler that calls Cascade may act on the request before and
+// after the wrapped handler serves it.
+//
+// Cascade returns false if r is not served by a handler wrapping another, in
+// which case it has no effect. I.e. the handler of "/admin/+" wraps the
+// handler of "/admin/users" when serving "/admin/users" but serves
+// "/admin/settings" itself if no more specific template matches it.
+func Cascade(w http.ResponseWriter, r *http.Request) bool {
+	var l, ok = r.Context().Value(layerKey).(*layer)
+	if !ok || l == nil {
+		return false
+	}
+	l.inner.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), layerKey, (*layer)(nil))))
+	return true
+}
+
+// cascadeRoute sets layers of rt registered for template in t and, if rt
+// is a layer, inserts it into layers of routes of templates it matches and
+// rewraps them. Caller must hold the lock.
+func (mux *ServeMux) cascadeRoute(t *table, template string, rt *route) {
+	var p, err = varouter.Parse(template, varouter.DefaultTokens)
+	if err != nil {
+		return
+	}
+	// Layers are the prefix templates matched by the path the template
+	// describes.
+	for _, segment := range p.Segments {
+		if segment.Kind != varouter.SegmentPrefix {
+			rt.path += segment.Text
+		}
+		if segment.Name != "" {
+			rt.depth++
+		}
+	}
+	_, isMount := rt.handler.(*mount)
+	rt.layer = rt.method == "" && !isMount && p.Prefix && !p.Override && !p.Exclusion
+	rt.layers = t.layersOf(template, rt.path)
+	var i = sort.Search(len(t.byPath), func(i int) bool { return t.byPath[i].path > rt.path })
+	t.byPath = append(t.byPath, nil)
+	copy(t.byPath[i+1:], t.byPath[i:])
+	t.byPath[i] = rt
+	if !rt.layer {
+		return
+	}
+	// Paths of templates rt matches start with its literal prefix.
+	var prefix string
+	for _, segment := range p.Segments {
+		if segment.Kind != varouter.SegmentLiteral {
+			break
+		}
+		prefix += segment.Text
+	}
+	var r = varouter.New()
+	if err = r.Register(template); err != nil {
+		return
+	}
+	i = sort.Search(len(t.byPath), func(i int) bool { return t.byPath[i].path >= prefix })
+	for _, route := range t.byPath[i:] {
+		if !strings.HasPrefix(route.path, prefix) {
+			break
+		}
+		if _, matched := splitHost(route.pattern); matched == template {
+			continue
+		}
+		if _, _, ok := r.Match(route.path); ok {
+			route.layers = insertLayer(route.layers, rt)
+			mux.wrapRoute(route)
+		}
+	}
+}
+
+// layersOf returns routes of layers wrapping handlers of template which
+// describes path, in order of template depth, the shallowest being the
+// outermost.
+func (t *table) layersOf(template, path string) (layers []*route) {
+	var templates, _, _ = t.r.Match(path)
+	for _, matched := range templates {
+		if matched == template {
+			continue
+		}
+		for _, route := range t.routes[matched] {
+			if route.layer {
+				layers = insertLayer(layers, route)
+			}
+		}
+	}
+	return
+}
+
+// insertLayer inserts rt into layers after layers of the same or lesser
+// depth and returns layers.
+func insertLayer(layers []*route, rt *route) []*route {
+	var i = sort.Search(len(layers), func(i int) bool { return layers[i].depth > rt.depth })
+	layers = append(layers, nil)
+	copy(layers[i+1:], layers[i:])
+	layers[i] = rt
+	return layers
+}
+
+// cascade returns handler wrapped in handlers of layers, the first being the
+// outermost.
+func cascade(layers []*route, handler http.Handler) http.Handler {
+	for i := len(layers) - 1; i >= 0; i-- {
+		handler = &layer{layers[i].handler, handler}
+	}
+	return handler
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// layerHandler returns a handler that writes tag and the pattern of the
+// request and serves the wrapped handler or responds as patternHandler if it
+// wraps none.
+func layerHandler(tag string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(tag + "(" + Pattern(r) + ") "))
+		if !Cascade(w, r) {
+			patternHandler()(w, r)
+		}
+	}
+}
+
+func TestCascade(t *testing.T) {
+	billing := NewServeMux()
+	billing.Handle("/+", patternHandler())
+	mux := NewServeMuxMode(ModeCascade)
+	mux.Use(tagMiddleware("log"))
+	mux.Handle("/admin/+", layerHandler("auth"))
+	mux.Handle("/admin/users+", layerHandler("users"))
+	mux.Handle("/+", layerHandler("root"))
+	mux.HandleMethod("GET", "/admin/users/:id", patternHandler("id"))
+	mux.HandleMethod("POST", "/admin/+", patternHandler())
+	mux.Mount("/billing/+", billing)
+	mux.Handle("/billing/report", patternHandler())
+	mux.Handle("api.example.org/+", layerHandler("host"))
+	mux.Handle("api.example.org/home", patternHandler())
+	for _, test := range []struct {
+		method, target, body string
+	}{
+		{"GET", "/admin/", "log(/admin/+) root(/admin/+) auth(/admin/+) /admin/+ /admin/"},
+		{"POST", "/admin/x", "log(/admin/+) root(/admin/+) /admin/+ /admin/x"},
+		{"GET", "/admin/users/1", "log(/admin/users/:id) root(/admin/users/:id) auth(/admin/users/:id) users(/admin/users/:id) /admin/users/:id /admin/users/1 id=1,1"},
+		{"POST", "/admin/users/1", "log(/admin/users+) root(/admin/users+) auth(/admin/users+) users(/admin/users+) /admin/users+ /admin/users/1"},
+		{"GET", "/billing/x", "log(/billing/+) root(/billing/+) /billing/+ /x"},
+		{"GET", "/billing/report", "log(/billing/report) root(/billing/report) /billing/report /billing/report"},
+		{"GET", "/other", "log(/+) root(/+) /+ /other"},
+		{"GET", "http://api.example.org/home", "log(api.example.org/home) host(api.example.org/home) api.example.org/home /home"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, w.Body.String(), test.body)
+		}
+	}
+	h, _, r := mux.Handler(httptest.NewRequest("GET", "/admin/users/1", nil))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if expected := "root(/admin/users/:id) auth(/admin/users/:id) users(/admin/users/:id) /admin/users/:id /admin/users/1 id=1,1"; w.Body.String() != expected {
+		t.Fatalf("Handler served '%s', expected '%s'", w.Body.String(), expected)
+	}
+}
+
+func TestCascadeOff(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/+", layerHandler("root"))
+	mux.Handle("/home", patternHandler())
+	for _, test := range []struct{ target, body string }{
+		{"/home", "/home /home"},
+		{"/other", "root(/+) /+ /other"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("GET %s served '%s', expected '%s'", test.target, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestCascadeFallthrough(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewServeMuxMode did not panic on ModeCascade and ModeFallthrough")
+		}
+	}()
+	NewServeMuxMode(ModeCascade | ModeFallthrough)
+}
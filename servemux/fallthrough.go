@@ -0,0 +1,114 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// nextKey is the context key of the fallthrough state of a request.
+var nextKey = placeholderKey{"varouter/servemux/next"}
+
+// next is the fallthrough state of a request served in ModeFallthrough.
+type next struct {
+	// candidates are the handlers to try.
+	candidates []candidate
+	// orig is the request candidate requests are derived from.
+	orig *http.Request
+	// declined specifies if the handler called Next.
+	declined bool
+}
+
+// Next declines serving r by the handler serving it in ModeFallthrough. After
+// the handler returns, the request is served by the handler of the next
+// matched template. A handler that calls Next must not write a response.
+//
+// Next returns false if r is not served in ModeFallthrough, in which case it
+// has no effect.
+func Next(r *http.Request) bool {
+	if state, ok := r.Context().Value(nextKey).(*next); ok {
+		state.declined = true
+		return true
+	}
+	return false
+}
+
+// candidate is a handler of a template matched by a request.
+type candidate struct {
+	handler http.Handler
+	pattern string
+	vars    varouter.Vars
+}
+
+// candidates returns handlers of all templates matching r which serve the
+// request method in order in which they are tried in ModeFallthrough. Caller
+// must hold the read lock.
+func (mux *ServeMux) candidates(r *http.Request) (candidates []candidate) {
+	if mux.hosts != nil {
+		var hosttemplates, hostvars, _ = mux.hosts.Match(hostTemplate(stripHostPort(r.Host)))
+		for i := len(hosttemplates) - 1; i >= 0; i-- {
+			candidates = mux.hostTables[hosttemplates[i]].candidates(r, hostvars, candidates)
+		}
+	}
+	return mux.table.candidates(r, nil, candidates)
+}
+
+// candidates appends handlers of templates in t matching r, in reverse order
+// of matched templates, to candidates and returns it.
+func (t *table) candidates(r *http.Request, hostvars varouter.Vars, candidates []candidate) []candidate {
+	var templates, vars, matched = t.r.Match(r.URL.Path)
+	if !matched {
+		return candidates
+	}
+	vars = mergeVars(hostvars, vars)
+	for i := len(templates) - 1; i >= 0; i-- {
+		if route := methodRoute(t.routes[templates[i]], r.Method); route != nil {
+			candidates = append(candidates, candidate{route.layered, route.pattern, vars})
+		}
+	}
+	return candidates
+}
+
+// fallthroughHandler returns a handler that serves r in ModeFallthrough
+// wrapped in middleware and the request to serve it with. Caller must hold
+// the read lock.
+func (mux *ServeMux) fallthroughHandler(r *http.Request) (http.Handler, *http.Request) {
+	if mux.redirects(r) {
+		if h, ok := cleanRedirect(r); ok {
+			return chain(mux.middleware, h), r
+		}
+	}
+	var candidates = mux.candidates(r)
+	if len(candidates) == 0 {
+		var h, pattern, vars = mux.handler(r, mux.middleware)
+		return h, mux.request(r, pattern, vars)
+	}
+	var state = &next{candidates: candidates}
+	state.orig = r.WithContext(context.WithValue(r.Context(), nextKey, state))
+	return mux.dispatch, mux.request(state.orig, candidates[0].pattern, candidates[0].vars)
+}
+
+// serveFallthrough serves r by the fallthrough candidates of r until one
+// serves it without calling Next.
+func (mux *ServeMux) serveFallthrough(w http.ResponseWriter, r *http.Request) {
+	var state = r.Context().Value(nextKey).(*next)
+	for i, c := range state.candidates {
+		// Requests of candidates after the first are derived from the
+		// original request so that variables of declined candidates are
+		// not carried over.
+		if i > 0 {
+			r = mux.request(state.orig, c.pattern, c.vars)
+		}
+		state.declined = false
+		c.handler.ServeHTTP(w, r)
+		if !state.declined {
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// declineHandler calls Next if the request path contains "skip" or responds
+// as patternHandler otherwise.
+func declineHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.URL.Path, "skip") {
+		if !Next(r) {
+			http.Error(w, "not in fallthrough mode", http.StatusInternalServerError)
+		}
+		return
+	}
+	patternHandler()(w, r)
+}
+
+func TestFallthrough(t *testing.T) {
+	mux := NewServeMuxMode(ModeFallthrough)
+	mux.Use(tagMiddleware("log"))
+	mux.Handle("/+", patternHandler())
+	mux.HandleFunc("/files/*.go", declineHandler)
+	mux.HandleMethod("POST", "/files/*.go", patternHandler())
+	mux.HandleFunc("!/skip/+", declineHandler)
+	for _, test := range []struct {
+		method, target, body string
+	}{
+		{"GET", "/files/main.go", "log(/files/*.go) /files/*.go /files/main.go"},
+		{"GET", "/files/skip.go", "log(/files/*.go) /+ /files/skip.go"},
+		{"POST", "/files/skip.go", "log(/files/*.go) /files/*.go /files/skip.go"},
+		{"GET", "/skip/all", "log(!/skip/+) 404 page not found\n"},
+		{"GET", "/other", "log(/+) /+ /other"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestFallthroughVars(t *testing.T) {
+	mux := NewServeMuxMode(ModeFallthrough)
+	mux.Handle("/+", patternHandler("tenant"))
+	mux.HandleFunc(":tenant.example.net/+", declineHandler)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://acme.example.net/skip", nil))
+	if expected := "/+ /skip tenant=,"; w.Body.String() != expected {
+		t.Fatalf("GET /skip served '%s', expected '%s'", w.Body.String(), expected)
+	}
+}
+
+func TestNext(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/skip", declineHandler)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/skip", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Next outside of ModeFallthrough served %d", w.Code)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// Middleware is a function that wraps a handler.
+type Middleware = func(http.Handler) http.Handler
+
+// Use appends middleware to the middleware applied by ServeHTTP to every
+// handler it serves, including "page not found" and "method not allowed"
+// handlers. Middleware is applied in order, the first being the outermost.
+//
+// Middleware can retrieve the pattern of the served handler using Pattern.
+func (mux *ServeMux) Use(mw ...Middleware) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.middleware = append(mux.middleware, mw...)
+	mux.wrap()
+}
+
+// wrap wraps handlers of mux in its middleware. Caller must hold the lock.
+func (mux *ServeMux) wrap() {
+	for _, t := range mux.tables() {
+		mux.wrapTable(t)
+	}
+	mux.notFound = chain(mux.middleware, http.NotFoundHandler())
+	mux.dispatch = chain(mux.middleware, http.HandlerFunc(mux.serveFallthrough))
+}
+
+// Group calls fn with a new Group whose patterns are prefixed with prefix.
+func (mux *ServeMux) Group(prefix string, fn func(g *Group)) {
+	fn(&Group{mux, mux.trimPrefix(prefix), nil})
+}
+
+// Group registers handlers on a ServeMux with a common path prefix and
+// middleware. Patterns registered through a group are registered on the
+// ServeMux with the path prefixed by the group prefix, after the method and
+// the host, if any. I.e. pattern "/users/:id" in a group with prefix
+// "/admin" registers "/admin/users/:id".
+type Group struct {
+	mux        *ServeMux
+	prefix     string
+	middleware []Middleware
+}
+
+// Group calls fn with a new Group nested in g whose prefix is appended to
+// the prefix of g and whose middleware is applied inside middleware of g.
+func (g *Group) Group(prefix string, fn func(g *Group)) {
+	var middleware = make([]Middleware, len(g.middleware))
+	copy(middleware, g.middleware)
+	fn(&Group{g.mux, g.prefix + g.mux.trimPrefix(prefix), middleware})
+}
+
+// Use appends middleware to the middleware of g. Middleware is applied to
+// handlers registered through g and its nested groups after the call, in
+// order, the first being the outermost.
+func (g *Group) Use(mw ...Middleware) { g.middleware = append(g.middleware, mw...) }
+
+// Handle registers the handler for the prefixed pattern.
+// See ServeMux.Handle.
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	g.HandleMethod("", pattern, handler)
+}
+
+// HandleFunc registers the handler function for the prefixed pattern.
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// HandleMethod registers the handler for the given method and prefixed
+// pattern. See ServeMux.HandleMethod.
+func (g *Group) HandleMethod(method, pattern string, handler http.Handler) {
+	g.mux.HandleMethod(method, g.mux.prefixPattern(g.prefix, pattern), chain(g.middleware, handler))
+}
+
+// HandleMethodFunc registers the handler function for the given method and
+// prefixed pattern.
+func (g *Group) HandleMethodFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.HandleMethod(method, pattern, http.HandlerFunc(handler))
+}
+
+// trimPrefix returns a group prefix without a trailing separator. It panics
+// if prefix does not start with a separator.
+func (mux *ServeMux) trimPrefix(prefix string) string {
+	if prefix == "" || prefix[0] != '/' {
+		panic("group prefix must start with '/'")
+	}
+	return strings.TrimRight(prefix, "/")
+}
+
+// prefixPattern returns pattern with prefix inserted before its path.
+func (mux *ServeMux) prefixPattern(prefix, pattern string) string {
+	if prefix == "" {
+		return pattern
+	}
+	if mux.mode&ModePatterns != 0 {
+		var method string
+		if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+			method, pattern = pattern[:i+1], strings.TrimLeft(pattern[i+1:], " \t")
+		}
+		if i := strings.IndexByte(pattern, '/'); i >= 0 {
+			return method + pattern[:i] + prefix + pattern[i:]
+		}
+		return method + pattern
+	}
+	var host, template = splitHost(pattern)
+	if t := varouter.DefaultTokens; template != "" && (template[0] == t.Override || template[0] == t.Exclusion) {
+		return host + template[:1] + prefix + template[1:]
+	}
+	return host + prefix + template
+}
+
+// wrapTable wraps handlers of routes of t. Caller must hold the lock.
+func (mux *ServeMux) wrapTable(t *table) {
+	for _, routes := range t.routes {
+		for _, route := range routes {
+			mux.wrapRoute(route)
+		}
+	}
+}
+
+// wrapRoute wraps the handler of rt in handlers of its layers and in
+// middleware of mux. Caller must hold the lock.
+func (mux *ServeMux) wrapRoute(rt *route) {
+	rt.layered = cascade(rt.layers, rt.handler)
+	rt.served = chain(mux.middleware, rt.layered)
+}
+
+// chain returns handler wrapped in middleware, the first being the
+// outermost.
+func chain(middleware []Middleware, handler http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tagMiddleware returns middleware that writes tag and the pattern of the
+// request before calling the next handler.
+func tagMiddleware(tag string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tag + "(" + Pattern(r) + ") "))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	mux := NewServeMux()
+	mux.Use(tagMiddleware("log"))
+	mux.Handle("/", varsHandler("/"))
+	mux.Group("/admin/", func(g *Group) {
+		g.Use(tagMiddleware("auth"))
+		g.Handle("/", varsHandler("/admin/"))
+		g.HandleMethod("POST", "/users/:id", varsHandler("POST /admin/users/:id", "id"))
+		g.Group("/audit", func(g *Group) {
+			g.Use(tagMiddleware("audit"))
+			g.Handle("/+", varsHandler("/admin/audit/+"))
+		})
+		g.Handle("!/settings", varsHandler("!/admin/settings"))
+	})
+	mux.Group("/api", func(g *Group) {
+		g.Handle(":tenant.example.com/items", varsHandler(":tenant.example.com/api/items", "tenant"))
+	})
+	for _, test := range []struct {
+		method, target, body string
+	}{
+		{"GET", "/", "log(/) /"},
+		{"GET", "/admin/", "log(/admin/) auth(/admin/) /admin/"},
+		{"POST", "/admin/users/1", "log(/admin/users/:id) auth(/admin/users/:id) POST /admin/users/:id id=1,1"},
+		{"GET", "/admin/users/1", "log() Method Not Allowed\n"},
+		{"GET", "/admin/audit/log", "log(/admin/audit/+) auth(/admin/audit/+) audit(/admin/audit/+) /admin/audit/+"},
+		{"GET", "/admin/settings", "log(!/admin/settings) auth(!/admin/settings) !/admin/settings"},
+		{"GET", "/missing", "log() 404 page not found\n"},
+		{"GET", "http://acme.example.com/api/items", "log(:tenant.example.com/api/items) :tenant.example.com/api/items tenant=acme,acme"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestGroupPatterns(t *testing.T) {
+	mux := NewServeMuxMode(ModePatterns)
+	mux.Group("/admin", func(g *Group) {
+		g.Use(tagMiddleware("auth"))
+		g.Handle("GET api.example.org/users/{id}", varsHandler("GET api.example.org/admin/users/{id}", "id"))
+		g.HandleMethod("POST", "/users/{id}", varsHandler("POST /admin/users/{id}", "id"))
+	})
+	for _, test := range []struct {
+		method, target, body string
+	}{
+		{"GET", "http://api.example.org/admin/users/1", "auth(GET api.example.org/admin/users/{id}) GET api.example.org/admin/users/{id} id=1,1"},
+		{"POST", "/admin/users/1", "auth(POST /admin/users/{id}) POST /admin/users/{id} id=1,1"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestUseAllocs(t *testing.T) {
+	passthrough := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next.ServeHTTP(w, r) })
+	}
+	for _, mode := range []Mode{0, ModeFallthrough, ModePatterns} {
+		var allocs [2]float64
+		for i := range allocs {
+			mux := NewServeMuxMode(mode)
+			if i > 0 {
+				mux.Use(passthrough, passthrough, passthrough)
+			}
+			mux.Handle("/items", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			for _, target := range []string{"/items", "/missing"} {
+				w, r := httptest.NewRecorder(), httptest.NewRequest("GET", target, nil)
+				allocs[i] += testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, r) })
+			}
+		}
+		if allocs[1] > allocs[0] {
+			t.Fatalf("ServeHTTP in mode %d allocated %v times with middleware, %v times without", mode, allocs[1], allocs[0])
+		}
+	}
+}
+
+func TestUseWraps(t *testing.T) {
+	var wraps int
+	counter := func(next http.Handler) http.Handler {
+		wraps++
+		return next
+	}
+	mux := NewServeMux()
+	mux.Use(counter)
+	wraps = 0
+	for _, pattern := range []string{"/a", "/b", "/c/:id", "api.example.org/d"} {
+		mux.Handle(pattern, patternHandler())
+	}
+	if wraps != 4 {
+		t.Fatalf("Registering 4 handlers applied middleware %d times, expected 4", wraps)
+	}
+}
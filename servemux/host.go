@@ -0,0 +1,87 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// splitHost splits a pattern into a host and a path template. A pattern has
+// a host if it does not start with a separator, override or exclusion
+// character and a separator follows the host.
+func splitHost(pattern string) (host, template string) {
+	var t = varouter.DefaultTokens
+	if pattern == "" || pattern[0] == t.Separator || pattern[0] == t.Override || pattern[0] == t.Exclusion {
+		return "", pattern
+	}
+	var i = strings.IndexByte(pattern, t.Separator)
+	if i < 0 {
+		return "", pattern
+	}
+	return pattern[:i], pattern[i:]
+}
+
+// hostTemplate returns host as a template of its labels in reverse order,
+// each prefixed with a dot, as matched by a host router. Literal labels are
+// converted to lower case. For example, ":tenant.Example.com" returns
+// ".com.example.:tenant".
+func hostTemplate(host string) string {
+	var sb strings.Builder
+	sb.Grow(len(host) + 1)
+	host = strings.TrimSuffix(host, ".")
+	for host != "" {
+		var label string
+		if i := strings.LastIndexByte(host, '.'); i >= 0 {
+			label, host = host[i+1:], host[:i]
+		} else {
+			label, host = host, ""
+		}
+		sb.WriteByte('.')
+		if label != "" && label[0] == varouter.DefaultTokens.Variable {
+			sb.WriteString(label)
+		} else {
+			sb.WriteString(strings.ToLower(label))
+		}
+	}
+	return sb.String()
+}
+
+// hostTable returns the table of routes for a host pattern, registering the
+// host if not registered. It panics if the host pattern is invalid.
+func (mux *ServeMux) hostTable(host string) *table {
+	if mux.hosts == nil {
+		mux.hosts = varouter.NewVarouter(false, '!', '.', ':', '+', '?', '*')
+		mux.hostTables = make(map[string]*table)
+	}
+	var template = hostTemplate(host)
+	if t, exists := mux.hostTables[template]; exists {
+		return t
+	}
+	if err := mux.hosts.Register(template); err != nil && !errors.Is(err, varouter.ErrDuplicate) {
+		panic(err)
+	}
+	var t = newTable(varouter.New())
+	mux.hostTables[template] = &t
+	return &t
+}
+
+// mergeVars returns path variables merged into host variables. Path variables
+// take precedence over host variables of the same name.
+func mergeVars(host, path varouter.Vars) varouter.Vars {
+	if len(host) == 0 {
+		return path
+	}
+	var vars = make(varouter.Vars, len(host)+len(path))
+	for name, value := range host {
+		vars[name] = value
+	}
+	for name, value := range path {
+		vars[name] = value
+	}
+	return vars
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostTemplate(t *testing.T) {
+	for _, test := range []struct{ host, template string }{
+		{"example.com", ".com.example"},
+		{"Example.COM.", ".com.example"},
+		{":Tenant.example.com", ".com.example.:Tenant"},
+		{"+.example.com", ".com.example.+"},
+		{"localhost", ".localhost"},
+	} {
+		if template := hostTemplate(test.host); template != test.template {
+			t.Fatalf("hostTemplate(%q) returned %q, expected %q", test.host, template, test.template)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/+", varsHandler("/+"))
+	mux.Handle("/users/:user", varsHandler("/users/:user", "user"))
+	mux.Handle("example.com/+", varsHandler("example.com/+"))
+	mux.Handle(":tenant.example.com/+", varsHandler(":tenant.example.com/+", "tenant"))
+	mux.Handle(":tenant.example.com/users/:user", varsHandler(":tenant.example.com/users/:user", "tenant", "user"))
+	mux.Handle(":tenant.example.com/items/:tenant", varsHandler(":tenant.example.com/items/:tenant", "tenant"))
+	mux.HandleMethod("POST", "api.example.net/items", varsHandler("POST api.example.net/items"))
+	mux.Handle("+.example.org/", varsHandler("+.example.org/"))
+	for _, test := range []struct {
+		host, target string
+		code         int
+		body         string
+	}{
+		{"example.com", "/", 200, "example.com/+"},
+		{"EXAMPLE.com:8080", "/users/1", 200, "example.com/+"},
+		{"acme.example.com", "/", 200, ":tenant.example.com/+ tenant=acme,acme"},
+		{"Acme.Example.com:443", "/users/1", 200, ":tenant.example.com/users/:user tenant=acme,acme user=1,1"},
+		{"acme.example.com", "/items/1", 200, ":tenant.example.com/items/:tenant tenant=1,1"},
+		{"api.example.net", "/items", 200, "POST api.example.net/items"},
+		{"api.example.net", "/other", 200, "/+"},
+		{"api.example.com", "/other", 200, ":tenant.example.com/+ tenant=api,api"},
+		{"a.b.example.org", "/", 200, "+.example.org/"},
+		{"a.b.example.org", "/other", 200, "/+"},
+		{"a.b.example.com", "/users/1", 200, "/users/:user user=1,1"},
+		{"example.net", "/other", 200, "/+"},
+	} {
+		r := httptest.NewRequest("POST", test.target, nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Fatalf("%s%s served %d '%s', expected %d '%s'", test.host, test.target,
+				w.Code, w.Body.String(), test.code, test.body)
+		}
+	}
+}
+
+func TestHostFallback(t *testing.T) {
+	for _, mode := range []Mode{0, ModeFallthrough} {
+		mux := NewServeMuxMode(mode)
+		mux.Handle("+.example.com/+", varsHandler("+.example.com/+"))
+		mux.Handle("api.example.com/x", varsHandler("api.example.com/x"))
+		for _, test := range []struct{ target, body string }{
+			{"http://api.example.com/x", "api.example.com/x"},
+			{"http://api.example.com/y", "+.example.com/+"},
+		} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+			if w.Body.String() != test.body {
+				t.Fatalf("GET %s served '%s', expected '%s'", test.target, w.Body.String(), test.body)
+			}
+		}
+	}
+}
+
+func TestHostInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("failed detecting invalid host template")
+		}
+	}()
+	mux := NewServeMux()
+	mux.Handle(":tenant.example.com/", varsHandler(""))
+	mux.Handle("api.example.com/", varsHandler(""))
+}
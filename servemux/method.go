@@ -0,0 +1,140 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// HandleMethod registers the handler for the given method and pattern. An
+// empty method registers the handler for any method not registered for
+// pattern. If a handler already exists for method and pattern, HandleMethod
+// panics.
+//
+// A request is served by the handler of the most specific matched pattern
+// that serves the request method. Less specific matched patterns, and
+// patterns without a host, are tried if more specific ones have handlers
+// registered for other methods only, as http.ServeMux does. A handler
+// registered for GET also serves HEAD requests unless a handler is
+// registered for HEAD. If all matched patterns have handlers registered for
+// other methods only, the request is answered with "405 Method Not
+// Allowed" and an Allow header listing the registered methods. OPTIONS
+// requests are answered with the Allow header unless a handler is registered
+// for OPTIONS or for any method, in which case that handler serves them.
+//
+// In ModePatterns the method is prepended to the pattern. If method is not
+// empty and pattern specifies a method, HandleMethod panics.
+func (mux *ServeMux) HandleMethod(method, pattern string, handler http.Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if method != "" && !isToken(method) {
+		panic(fmt.Sprintf("invalid method %q", method))
+	}
+	if mux.mode&ModePatterns != 0 {
+		if method != "" {
+			if strings.ContainsAny(pattern, " \t") {
+				panic(fmt.Sprintf("pattern %q must not specify a method", pattern))
+			}
+			pattern = method + " " + pattern
+		}
+		if err := mux.registerPattern(pattern, handler); err != nil {
+			panic(err)
+		}
+		return
+	}
+	var host, template = splitHost(pattern)
+	var t = &mux.table
+	if host != "" {
+		t = mux.hostTable(host)
+	}
+	if err := t.r.Register(template); err != nil {
+		if _, exists := t.routes[template]; !exists || !errors.Is(err, varouter.ErrDuplicate) {
+			panic(err)
+		}
+	}
+	for _, route := range t.routes[template] {
+		if route.method == method {
+			panic(fmt.Sprintf("handler for method %q and pattern %q already exists", method, pattern))
+		}
+	}
+	var rt = &route{pattern: pattern, method: method, handler: handler}
+	t.routes[template] = append(t.routes[template], rt)
+	if mux.mode&ModeCascade != 0 {
+		mux.cascadeRoute(t, template, rt)
+	}
+	mux.wrapRoute(rt)
+}
+
+// HandleMethodFunc registers the handler function for the given method and
+// pattern.
+func (mux *ServeMux) HandleMethodFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	mux.HandleMethod(method, pattern, http.HandlerFunc(handler))
+}
+
+// matchMethod returns if rt serves method. Routes registered for GET also
+// serve HEAD.
+func (rt *route) matchMethod(method string) bool {
+	return rt.method == "" || rt.method == method || rt.method == http.MethodGet && method == http.MethodHead
+}
+
+// methodRoute returns the route of routes registered for a pattern that
+// serves method or nil if none. A route registered for method takes
+// precedence over a GET route serving HEAD which takes precedence over a
+// route registered for any method.
+func methodRoute(routes []*route, method string) (best *route) {
+	for _, route := range routes {
+		switch {
+		case route.method == method:
+			return route
+		case route.method == http.MethodGet && method == http.MethodHead:
+			best = route
+		case route.method == "" && best == nil:
+			best = route
+		}
+	}
+	return
+}
+
+// allowedMethods returns methods served by routes and OPTIONS, sorted.
+func allowedMethods(routes []*route) []string {
+	var allow = []string{http.MethodOptions}
+	for _, route := range routes {
+		allow = append(allow, route.method)
+		if route.method == http.MethodGet {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	sort.Strings(allow)
+	var i int
+	for _, method := range allow {
+		if i == 0 || allow[i-1] != method {
+			allow[i] = method
+			i++
+		}
+	}
+	return allow[:i]
+}
+
+// methodHandler returns the handler answering a request whose method is not
+// served by the matched pattern with methods allowed by it. OPTIONS requests
+// are answered with "204 No Content", others with "405 Method Not Allowed".
+func methodHandler(method string, allow []string) http.Handler {
+	var header = strings.Join(allow, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", header)
+		if method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleMethod(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleMethod("GET", "/+", varsHandler("GET /+"))
+	mux.HandleMethod("GET", "/items/:id", varsHandler("GET /items/:id", "id"))
+	mux.HandleMethod("POST", "/items/:id", varsHandler("POST /items/:id", "id"))
+	mux.HandleMethod("DELETE", "/items/:id", varsHandler("DELETE /items/:id", "id"))
+	mux.HandleMethod("PUT", "/users", varsHandler("PUT /users"))
+	mux.Handle("/users", varsHandler("/users"))
+	mux.Handle("/docs+", varsHandler("/docs+"))
+	mux.HandleMethod("POST", "/docs/new", varsHandler("POST /docs/new"))
+	for _, test := range []struct {
+		method, target string
+		code           int
+		body, allow    string
+	}{
+		{"GET", "/items/1", 200, "GET /items/:id id=1,1", ""},
+		{"HEAD", "/items/1", 200, "GET /items/:id id=1,1", ""},
+		{"POST", "/items/1", 200, "POST /items/:id id=1,1", ""},
+		{"PATCH", "/items/1", 405, "Method Not Allowed\n", "DELETE, GET, HEAD, OPTIONS, POST"},
+		{"OPTIONS", "/items/1", 204, "", "DELETE, GET, HEAD, OPTIONS, POST"},
+		{"PUT", "/users", 200, "PUT /users", ""},
+		{"PATCH", "/users", 200, "/users", ""},
+		{"OPTIONS", "/users", 200, "/users", ""},
+		{"POST", "/docs/new", 200, "POST /docs/new", ""},
+		{"GET", "/docs/new", 200, "/docs+", ""},
+		{"GET", "/other", 200, "GET /+", ""},
+		{"PATCH", "/other", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Code != test.code || w.Body.String() != test.body || w.Header().Get("Allow") != test.allow {
+			t.Fatalf("%s %s served %d '%s' Allow '%s', expected %d '%s' Allow '%s'", test.method, test.target,
+				w.Code, w.Body.String(), w.Header().Get("Allow"), test.code, test.body, test.allow)
+		}
+	}
+}
+
+func TestHandleMethodPatterns(t *testing.T) {
+	mux := NewServeMuxMode(ModePatterns)
+	mux.HandleMethod("GET", "/items/{id}", varsHandler("GET /items/{id}", "id"))
+	mux.HandleMethod("POST", "/items/{id}", varsHandler("POST /items/{id}", "id"))
+	mux.HandleMethod("PUT", "/items/new", varsHandler("PUT /items/new"))
+	for _, test := range []struct {
+		method, target string
+		code           int
+		allow          string
+	}{
+		{"GET", "/items/1", 200, ""},
+		{"DELETE", "/items/1", 405, "GET, HEAD, OPTIONS, POST"},
+		{"DELETE", "/items/new", 405, "GET, HEAD, OPTIONS, POST, PUT"},
+		{"OPTIONS", "/items/new", 204, "GET, HEAD, OPTIONS, POST, PUT"},
+		{"GET", "/users", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Code != test.code || w.Header().Get("Allow") != test.allow {
+			t.Fatalf("%s %s served %d Allow '%s', expected %d Allow '%s'", test.method, test.target,
+				w.Code, w.Header().Get("Allow"), test.code, test.allow)
+		}
+	}
+}
+
+func TestHandleMethodDuplicate(t *testing.T) {
+	for _, test := range []struct {
+		mode     Mode
+		register func(mux *ServeMux)
+	}{
+		{0, func(mux *ServeMux) { mux.HandleMethod("GET", "/items", http.NotFoundHandler()) }},
+		{0, func(mux *ServeMux) { mux.HandleMethod("GE T", "/users", http.NotFoundHandler()) }},
+		{ModePatterns, func(mux *ServeMux) { mux.HandleMethod("GET", "GET /users", http.NotFoundHandler()) }},
+		{ModePatterns, func(mux *ServeMux) { mux.HandleMethod("POST", "GET /users", http.NotFoundHandler()) }},
+	} {
+		mux := NewServeMuxMode(test.mode)
+		mux.HandleMethod("GET", "/items", http.NotFoundHandler())
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("HandleMethod did not panic.")
+				}
+			}()
+			test.register(mux)
+		}()
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// mountKey is the context key of the path prefix of mounted ServeMux.
+var mountKey = placeholderKey{"varouter/servemux/mount"}
+
+// mount is a handler that serves requests using a mounted ServeMux.
+type mount struct {
+	// prefix is the path prefix stripped from request paths.
+	prefix string
+	// elements is the number of path elements in prefix.
+	elements int
+	// sub is the mounted ServeMux.
+	sub *ServeMux
+}
+
+// Mount registers sub to serve requests matching pattern, which must be a
+// prefix template such as "/billing/+", or in ModePatterns a pattern ending
+// with a slash such as "/billing/".
+//
+// Path elements matched by pattern, excluding the last, prefix matched one,
+// are stripped from the request path and the remainder is served by sub.
+// I.e. "/billing/invoices/1" is served by sub as "/invoices/1". Variables
+// parsed by the ServeMux are merged with variables parsed by sub, which take
+// precedence. Pattern returns the pattern of the sub handler prefixed with
+// the stripped path, i.e. "/billing/invoices/:id".
+//
+// Mount panics if pattern is not a prefix pattern or if sub is mux or mounts
+// mux, directly or through other mounted ServeMux.
+func (mux *ServeMux) Mount(pattern string, sub *ServeMux) {
+	var prefix, elements, err = mux.mountPrefix(pattern)
+	if err != nil {
+		panic(err)
+	}
+	if sub.mounts(mux) {
+		panic(fmt.Sprintf("mounting %q creates a mount cycle", pattern))
+	}
+	mux.Handle(pattern, &mount{prefix, elements, sub})
+}
+
+// mounts returns if mux is target or mounts target, directly or through
+// other mounted ServeMux.
+func (mux *ServeMux) mounts(target *ServeMux) bool {
+	if mux == target {
+		return true
+	}
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	for _, t := range mux.tables() {
+		for _, routes := range t.routes {
+			for _, route := range routes {
+				if m, ok := route.handler.(*mount); ok && m.sub.mounts(target) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// mountPrefix returns the path prefix of path elements matched by mount
+// pattern s, excluding a trailing separator and a last, prefix matched element
+// and the number of elements in the prefix.
+func (mux *ServeMux) mountPrefix(s string) (prefix string, elements int, err error) {
+	if mux.mode&ModePatterns != 0 {
+		var p, err = parsePattern(s)
+		if err != nil {
+			return "", 0, err
+		}
+		var last = p.segments[len(p.segments)-1]
+		if !last.multi || last.s != "" {
+			return "", 0, fmt.Errorf("mount pattern %q must end with a slash", s)
+		}
+		var i = strings.IndexByte(p.str, '/')
+		return p.str[i : len(p.str)-1], len(p.segments) - 1, nil
+	}
+	var _, template = splitHost(s)
+	var t *varouter.Template
+	if t, err = varouter.Parse(template, varouter.DefaultTokens); err != nil {
+		return
+	}
+	if !t.Prefix || t.Override || t.Exclusion {
+		return "", 0, fmt.Errorf("mount pattern %q must be a prefix template", s)
+	}
+	var segments = t.Segments[:len(t.Segments)-1]
+	if len(segments) > 0 {
+		// The last element is either the trailing separator or matched by
+		// prefix. Empty elements before it are part of the prefix.
+		segments = segments[:len(segments)-1]
+	}
+	for _, segment := range segments {
+		prefix += segment.Text
+		elements++
+	}
+	return
+}
+
+// ServeHTTP implements http.Handler.
+func (m *mount) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var rest = r.URL.Path
+	for i := 0; i < m.elements && rest != ""; i++ {
+		var j = strings.IndexByte(rest[1:], '/')
+		if j < 0 {
+			rest = ""
+			break
+		}
+		rest = rest[j+1:]
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	var r2 = new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = rest
+	r2.URL.RawPath = ""
+	r2 = r2.WithContext(context.WithValue(r.Context(), mountKey, mountedPrefix(r)+m.prefix))
+	m.sub.ServeHTTP(w, r2)
+}
+
+// mountedPrefix returns the path prefix stripped from r by mounts.
+func mountedPrefix(r *http.Request) string {
+	prefix, _ := r.Context().Value(mountKey).(string)
+	return prefix
+}
+
+// Patterns returns registered patterns sorted. Patterns of mounted ServeMux
+// are returned in place of their mount pattern, prefixed with the mount
+// path, i.e. "/billing/invoices/:id".
+func (mux *ServeMux) Patterns() (patterns []string) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	for _, t := range mux.tables() {
+		for _, routes := range t.routes {
+			for _, route := range routes {
+				if m, ok := route.handler.(*mount); ok {
+					for _, pattern := range m.sub.Patterns() {
+						patterns = append(patterns, m.sub.prefixPattern(m.prefix, pattern))
+					}
+					continue
+				}
+				patterns = append(patterns, route.pattern)
+			}
+		}
+	}
+	sort.Strings(patterns)
+	return
+}
+
+// tables returns the table without a host and host tables of mux. Caller
+// must hold the read lock.
+func (mux *ServeMux) tables() []*table {
+	var tables = []*table{&mux.table}
+	for _, t := range mux.hostTables {
+		tables = append(tables, t)
+	}
+	return tables
+}
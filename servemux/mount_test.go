@@ -0,0 +1,117 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// patternHandler responds with the Pattern of the request, its path and the
+// Placeholders and path values of the specified variable names.
+func patternHandler(names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		varsHandler(Pattern(r)+" "+r.URL.Path, names...)(w, r)
+	}
+}
+
+func TestMount(t *testing.T) {
+	invoices := NewServeMux()
+	invoices.Handle("/:id", patternHandler("tenant", "id"))
+	billing := NewServeMux()
+	billing.Handle("/", patternHandler())
+	billing.HandleMethod("GET", "/invoices/:id", patternHandler("tenant", "id"))
+	billing.Mount("/accounts/+", invoices)
+	mux := NewServeMux()
+	mux.Handle("/+", patternHandler())
+	mux.Mount("/t/:tenant/billing/+", billing)
+	for _, test := range []struct {
+		method, target, body string
+	}{
+		{"GET", "/t/acme/billing/", "/t/:tenant/billing/ /"},
+		{"GET", "/t/acme/billing/invoices/1", "/t/:tenant/billing/invoices/:id /invoices/1 tenant=acme,acme id=1,1"},
+		{"GET", "/t/acme/billing/accounts/7", "/t/:tenant/billing/accounts/:id /7 tenant=acme,acme id=7,7"},
+		{"POST", "/t/acme/billing/invoices/1", "Method Not Allowed\n"},
+		{"GET", "/t/acme/billing/other", "404 page not found\n"},
+		{"GET", "/t/acme/billing", "/+ /t/acme/billing"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, w.Body.String(), test.body)
+		}
+	}
+	expected := []string{
+		"/+",
+		"/t/:tenant/billing/",
+		"/t/:tenant/billing/accounts/:id",
+		"/t/:tenant/billing/invoices/:id",
+	}
+	if patterns := mux.Patterns(); !reflect.DeepEqual(patterns, expected) {
+		t.Fatalf("Patterns returned %v, expected %v", patterns, expected)
+	}
+}
+
+func TestMountEmptyElement(t *testing.T) {
+	billing := NewServeMux()
+	billing.Handle("/invoices/:id", patternHandler("id"))
+	mux := NewServeMux()
+	mux.Mount("/a//b/+", billing)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/a//b/invoices/7", nil))
+	if body := w.Body.String(); body != "/a//b/invoices/:id /invoices/7 id=7,7" {
+		t.Fatalf("Mount served '%s'", body)
+	}
+}
+
+func TestMountPatterns(t *testing.T) {
+	billing := NewServeMuxMode(ModePatterns)
+	billing.Handle("GET /invoices/{id}", patternHandler("id"))
+	mux := NewServeMuxMode(ModePatterns)
+	mux.Mount("/billing/", billing)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/billing/invoices/1", nil))
+	if body := w.Body.String(); body != "GET /billing/invoices/{id} /invoices/1 id=1,1" {
+		t.Fatalf("Mount served '%s'", body)
+	}
+}
+
+func TestMountInvalid(t *testing.T) {
+	for _, mux := range []*ServeMux{NewServeMux(), NewServeMuxMode(ModePatterns)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("failed detecting invalid mount pattern")
+				}
+			}()
+			mux.Mount("/billing", NewServeMux())
+		}()
+	}
+}
+
+func TestMountCycle(t *testing.T) {
+	a, b, c := NewServeMux(), NewServeMux(), NewServeMux()
+	a.Mount("/b/+", b)
+	b.Mount("/c/+", c)
+	for _, mount := range []func(){
+		func() { a.Mount("/a/+", a) },
+		func() { c.Mount("/a/+", a) },
+		func() { c.Mount("/b/+", b) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("failed detecting mount cycle")
+				}
+			}()
+			mount()
+		}()
+	}
+	if patterns := a.Patterns(); len(patterns) != 0 {
+		t.Fatalf("Patterns returned '%v'", patterns)
+	}
+}
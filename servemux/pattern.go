@@ -0,0 +1,375 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/vedranvuk/varouter"
+)
+
+// patternTokens are the tokens of the Varouter used in ModePatterns. They are
+// control characters which do not appear in patterns so that literal pattern
+// segments are never parsed as varouter tokens.
+var patternTokens = varouter.Tokens{
+	Override:     '\x01',
+	Exclusion:    '\x02',
+	Separator:    '/',
+	Variable:     '\x03',
+	Prefix:       '\x04',
+	WildcardOne:  '\x05',
+	WildcardMany: '\x06',
+}
+
+// newPatternRouter returns a new *Varouter using patternTokens.
+func newPatternRouter() *varouter.Varouter {
+	var t = patternTokens
+	var vr = varouter.NewVarouter(false, t.Override, t.Separator, t.Variable,
+		t.Prefix, t.WildcardOne, t.WildcardMany)
+	vr.SetExclusion(t.Exclusion)
+	return vr
+}
+
+// pattern is a parsed Go 1.22 net/http ServeMux pattern.
+type pattern struct {
+	str      string    // str is the pattern as registered.
+	method   string    // method, if not empty, is the matched method.
+	host     string    // host, if not empty, is the matched host.
+	segments []segment // segments are the path segments.
+}
+
+// segment is a pattern path segment.
+type segment struct {
+	s     string // s is the literal or wildcard name, "/" for {$}.
+	wild  bool   // wild specifies if the segment is a wildcard.
+	multi bool   // multi specifies if the wildcard matches the rest of a path.
+}
+
+// parsePattern parses a Go 1.22 net/http ServeMux pattern of the form
+// "[METHOD ][HOST]/[PATH]".
+func parsePattern(s string) (p *pattern, err error) {
+	if s == "" {
+		return nil, errors.New("empty pattern")
+	}
+	var offset int
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("parsing %q at offset %d: %w", s, offset, err)
+		}
+	}()
+	p = &pattern{str: s}
+	var rest = s
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		p.method, rest = s[:i], strings.TrimLeft(s[i+1:], " \t")
+		if !isToken(p.method) {
+			return nil, fmt.Errorf("invalid method %q", p.method)
+		}
+		offset = len(s) - len(rest)
+	}
+	var i = strings.IndexByte(rest, '/')
+	if i < 0 {
+		return nil, errors.New("host/path missing /")
+	}
+	p.host, rest = rest[:i], rest[i:]
+	if j := strings.IndexByte(p.host, '{'); j >= 0 {
+		offset += j
+		return nil, errors.New("host contains '{' (missing initial '/'?)")
+	}
+	offset += i
+	if p.method != "CONNECT" && rest != cleanPath(rest) {
+		return nil, errors.New("non-CONNECT pattern with unclean path can never match")
+	}
+	var seen = make(map[string]bool)
+	for len(rest) > 0 {
+		rest = rest[1:]
+		offset = len(s) - len(rest)
+		if rest == "" {
+			// Trailing slash matches the rest of a path.
+			p.segments = append(p.segments, segment{wild: true, multi: true})
+			break
+		}
+		if i = strings.IndexByte(rest, '/'); i < 0 {
+			i = len(rest)
+		}
+		var seg string
+		seg, rest = rest[:i], rest[i:]
+		if i = strings.IndexByte(seg, '{'); i < 0 {
+			if seg, err = url.PathUnescape(seg); err != nil {
+				return nil, err
+			}
+			p.segments = append(p.segments, segment{s: seg})
+			continue
+		}
+		if i != 0 {
+			return nil, errors.New("bad wildcard segment (must start with '{')")
+		}
+		if seg[len(seg)-1] != '}' {
+			return nil, errors.New("bad wildcard segment (must end with '}')")
+		}
+		var name = seg[1 : len(seg)-1]
+		if name == "$" {
+			if rest != "" {
+				return nil, errors.New("{$} not at end")
+			}
+			p.segments = append(p.segments, segment{s: "/"})
+			break
+		}
+		var multi = strings.HasSuffix(name, "...")
+		if multi {
+			name = name[:len(name)-3]
+			if rest != "" {
+				return nil, errors.New("{...} wildcard not at end")
+			}
+		}
+		if name == "" {
+			return nil, errors.New("empty wildcard")
+		}
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("bad wildcard name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate wildcard name %q", name)
+		}
+		seen[name] = true
+		p.segments = append(p.segments, segment{s: name, wild: true, multi: multi})
+	}
+	return p, nil
+}
+
+// template returns the varouter template, using patternTokens, that matches
+// all paths p matches. Wildcards and {$} are registered as wildcard elements
+// and multi wildcards as a prefix so that paths are matched exactly by match.
+func (p *pattern) template() string {
+	var sb strings.Builder
+	for _, seg := range p.segments {
+		sb.WriteByte(patternTokens.Separator)
+		switch {
+		case seg.multi:
+			sb.WriteByte(patternTokens.Prefix)
+		case seg.wild, seg.s == "/":
+			sb.WriteByte(patternTokens.WildcardMany)
+		default:
+			sb.WriteString(seg.s)
+		}
+	}
+	return sb.String()
+}
+
+// match matches path against p and returns values of named wildcards in
+// order of segments and a truth if matched.
+func (p *pattern) match(path string) (values []string, matched bool) {
+	var rest = path
+	for _, seg := range p.segments {
+		if rest == "" || rest[0] != '/' {
+			return nil, false
+		}
+		rest = rest[1:]
+		if seg.multi {
+			if seg.s != "" {
+				values = append(values, rest)
+			}
+			return values, true
+		}
+		var i = strings.IndexByte(rest, '/')
+		if i < 0 {
+			i = len(rest)
+		}
+		switch {
+		case seg.s == "/" && !seg.wild:
+			return values, rest == ""
+		case seg.wild:
+			if i == 0 {
+				return nil, false
+			}
+			values = append(values, rest[:i])
+		case rest[:i] != seg.s:
+			return nil, false
+		}
+		rest = rest[i:]
+	}
+	return values, rest == ""
+}
+
+// vars returns values returned by match mapped to wildcard names.
+func (p *pattern) vars(values []string) (vars varouter.Vars) {
+	if len(values) == 0 {
+		return nil
+	}
+	vars = make(varouter.Vars, len(values))
+	var i int
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			vars[seg.s] = values[i]
+			i++
+		}
+	}
+	return vars
+}
+
+// relationship is the relationship of sets of requests matched by two
+// patterns.
+type relationship int
+
+const (
+	equivalent   relationship = iota // Both patterns match the same requests.
+	moreGeneral                      // The first pattern matches a superset.
+	moreSpecific                     // The first pattern matches a subset.
+	disjoint                         // No request matches both patterns.
+	overlaps                         // Some but not all requests match both.
+)
+
+// inverse returns the inverse of a relationship.
+func (r relationship) inverse() relationship {
+	switch r {
+	case moreGeneral:
+		return moreSpecific
+	case moreSpecific:
+		return moreGeneral
+	}
+	return r
+}
+
+// combine returns the relationship of two patterns whose parts relate by r1
+// and r2.
+func combine(r1, r2 relationship) relationship {
+	switch r1 {
+	case equivalent:
+		return r2
+	case disjoint:
+		return disjoint
+	case overlaps:
+		if r2 == disjoint {
+			return disjoint
+		}
+		return overlaps
+	}
+	switch r2 {
+	case equivalent:
+		return r1
+	case r1.inverse():
+		return overlaps
+	}
+	return r2
+}
+
+// conflictsWith returns if p and q match some request and neither takes
+// precedence. Patterns with different hosts never conflict as a pattern with
+// a host takes precedence over one without.
+func (p *pattern) conflictsWith(q *pattern) bool {
+	if p.host != q.host {
+		return false
+	}
+	var rel = p.compare(q)
+	return rel == equivalent || rel == overlaps
+}
+
+// compare returns the relationship of methods and paths of p and q.
+func (p *pattern) compare(q *pattern) relationship {
+	return combine(p.compareMethods(q), p.comparePaths(q))
+}
+
+// compareMethods returns the relationship of methods of p and q.
+func (p *pattern) compareMethods(q *pattern) relationship {
+	switch {
+	case p.method == q.method:
+		return equivalent
+	case p.method == "":
+		return moreGeneral
+	case q.method == "":
+		return moreSpecific
+	case p.method == http.MethodGet && q.method == http.MethodHead:
+		return moreGeneral
+	case q.method == http.MethodGet && p.method == http.MethodHead:
+		return moreSpecific
+	}
+	return disjoint
+}
+
+// comparePaths returns the relationship of paths of p and q.
+func (p *pattern) comparePaths(q *pattern) relationship {
+	var plast, qlast = p.segments[len(p.segments)-1], q.segments[len(q.segments)-1]
+	if len(p.segments) != len(q.segments) && !plast.multi && !qlast.multi {
+		return disjoint
+	}
+	var rel = equivalent
+	var i int
+	for ; i < len(p.segments) && i < len(q.segments); i++ {
+		if rel = combine(rel, compareSegments(p.segments[i], q.segments[i])); rel == disjoint {
+			return rel
+		}
+	}
+	switch {
+	case len(p.segments) == len(q.segments):
+		return rel
+	case len(p.segments) < len(q.segments) && plast.multi:
+		return combine(rel, moreGeneral)
+	case len(q.segments) < len(p.segments) && qlast.multi:
+		return combine(rel, moreSpecific)
+	}
+	return disjoint
+}
+
+// compareSegments returns the relationship of segments s1 and s2.
+func compareSegments(s1, s2 segment) relationship {
+	switch {
+	case s1.multi && s2.multi:
+		return equivalent
+	case s1.multi:
+		return moreGeneral
+	case s2.multi:
+		return moreSpecific
+	case s1.wild && s2.wild:
+		return equivalent
+	case s1.wild:
+		if s2.s == "/" {
+			return disjoint
+		}
+		return moreGeneral
+	case s2.wild:
+		if s1.s == "/" {
+			return disjoint
+		}
+		return moreSpecific
+	case s1.s == s2.s:
+		return equivalent
+	}
+	return disjoint
+}
+
+// cleanPath returns p cleaned of "." and ".." elements and multiple
+// slashes, retaining a trailing slash.
+func cleanPath(p string) string {
+	var np = path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+// isIdentifier returns if s is a Go identifier.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isToken returns if s is a valid HTTP token, as used for methods.
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?={}`, c) >= 0 {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newPatternTestMux returns a ModePatterns mux with handlers for patterns that
+// respond with the pattern and values of specified path wildcards.
+func newPatternTestMux(patterns ...string) *ServeMux {
+	mux := NewServeMuxMode(ModePatterns)
+	for _, pattern := range patterns {
+		pattern := pattern
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, pattern)
+			for _, name := range []string{"id", "path", "name"} {
+				if value := r.PathValue(name); value != "" {
+					fmt.Fprintf(w, " %s=%s", name, value)
+				}
+			}
+		})
+	}
+	return mux
+}
+
+// serve serves a request to mux and returns the response code and body.
+func serve(mux http.Handler, method, target string) (int, string) {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestPatterns(t *testing.T) {
+	mux := newPatternTestMux(
+		"/",
+		"/{$}",
+		"/items/",
+		"/items/{id}",
+		"/items/new",
+		"GET /items/{id}/edit",
+		"POST /items/{id}/edit",
+		"/files/{path...}",
+		"api.example.org/",
+		"api.example.org/items/{id}",
+		"/users/{name}/{$}",
+	)
+	for _, test := range []struct {
+		method, target, expected string
+	}{
+		{"GET", "/", "/{$}"},
+		{"GET", "/other", "/"},
+		{"GET", "/items", "/"},
+		{"GET", "/items/", "/items/"},
+		{"GET", "/items/a/b", "/items/"},
+		{"GET", "/items/42", "/items/{id} id=42"},
+		{"GET", "/items/new", "/items/new"},
+		{"GET", "/items/42/edit", "GET /items/{id}/edit id=42"},
+		{"HEAD", "/items/42/edit", "GET /items/{id}/edit id=42"},
+		{"POST", "/items/42/edit", "POST /items/{id}/edit id=42"},
+		{"PUT", "/items/42/edit", "/items/"},
+		{"GET", "/files/", "/files/{path...}"},
+		{"GET", "/files/a/b.txt", "/files/{path...} path=a/b.txt"},
+		{"GET", "http://api.example.org/", "api.example.org/"},
+		{"GET", "http://api.example.org:8080/other", "api.example.org/"},
+		{"GET", "http://api.example.org/items/42", "api.example.org/items/{id} id=42"},
+		{"GET", "/users/vedran/", "/users/{name}/{$} name=vedran"},
+		{"GET", "/users/vedran/x", "/"},
+	} {
+		if _, body := serve(mux, test.method, test.target); body != test.expected {
+			t.Fatalf("%s %s served '%s', expected '%s'", test.method, test.target, body, test.expected)
+		}
+	}
+	if h, pattern, _ := mux.Handler(httptest.NewRequest("GET", "/items/42", nil)); h == nil || pattern != "/items/{id}" {
+		t.Fatalf("Handler returned pattern '%s'", pattern)
+	}
+}
+
+func TestPatternsNotFound(t *testing.T) {
+	mux := newPatternTestMux("/items/{id}", "GET /users/{$}")
+	for _, target := range []string{"/", "/items/", "/items/42/", "/users"} {
+		if code, _ := serve(mux, "GET", target); code != http.StatusNotFound {
+			t.Fatalf("GET %s served %d, expected %d", target, code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestPatternConflicts(t *testing.T) {
+	for _, test := range []struct {
+		registered, pattern string
+	}{
+		{"/items/{id}", "/items/{name}"},
+		{"/a/{x}", "/{y}/b"},
+		{"/files/", "/files/{path...}"},
+		{"GET /items", "GET /items"},
+		{"GET /a/{x}", "/{y}/b"},
+		{"api.example.org/{x}", "api.example.org/{y}"},
+	} {
+		mux := newPatternTestMux(test.registered)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Registering %q after %q did not panic.", test.pattern, test.registered)
+				}
+			}()
+			mux.HandleFunc(test.pattern, func(http.ResponseWriter, *http.Request) {})
+		}()
+	}
+	// Patterns that do not conflict.
+	newPatternTestMux("/items/{id}", "/items/new", "GET /items/new", "api.example.org/items/{id}", "/items/{id}/{$}")
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"GET",
+		"GE(T /",
+		"api.example.org",
+		"{host}/",
+		"/a/../b",
+		"/a//b",
+		"/a{x}",
+		"/{x",
+		"/{}",
+		"/{x-y}",
+		"/{x}/{x}",
+		"/{$}/a",
+		"/{x...}/a",
+	} {
+		if _, err := parsePattern(pattern); err == nil {
+			t.Fatalf("parsePattern(%q) did not fail.", pattern)
+		}
+	}
+}
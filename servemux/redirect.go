@@ -0,0 +1,60 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redirects returns if r may be redirected to a canonical path. As in
+// http.ServeMux, the path "*" of i.e. "OPTIONS *" requests is not
+// redirected.
+func (mux *ServeMux) redirects(r *http.Request) bool {
+	return mux.mode&ModeRedirect != 0 && r.Method != http.MethodConnect && r.URL.Path != "*" && mountedPrefix(r) == ""
+}
+
+// cleanRedirect returns a handler redirecting r to its cleaned path and a
+// truth if r.URL.Path is not clean.
+func cleanRedirect(r *http.Request) (http.Handler, bool) {
+	var p = r.URL.Path
+	if p == "" {
+		p = "/"
+	} else {
+		if p[0] != '/' {
+			p = "/" + p
+		}
+		p = cleanPath(p)
+	}
+	if p == r.URL.Path {
+		return nil, false
+	}
+	return redirectHandler(r, p), true
+}
+
+// slashRedirect returns a handler redirecting r to its path with a trailing
+// separator and a truth if the path with a trailing separator matches a
+// pattern. Caller must hold the read lock.
+func (mux *ServeMux) slashRedirect(r *http.Request) (http.Handler, bool) {
+	if strings.HasSuffix(r.URL.Path, "/") {
+		return nil, false
+	}
+	var u = *r.URL
+	u.Path += "/"
+	var r2 = *r
+	r2.URL = &u
+	if _, _, _, found := mux.lookup(&r2, nil); !found {
+		return nil, false
+	}
+	return redirectHandler(r, u.Path), true
+}
+
+// redirectHandler returns a handler that redirects r to path, preserving
+// the query.
+func redirectHandler(r *http.Request, path string) http.Handler {
+	var u = url.URL{Path: path, RawQuery: r.URL.RawQuery}
+	return http.RedirectHandler(u.String(), http.StatusMovedPermanently)
+}
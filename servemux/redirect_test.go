@@ -0,0 +1,96 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package servemux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	var muxes []*ServeMux
+	for _, mode := range []Mode{ModeRedirect, ModeRedirect | ModeFallthrough} {
+		mux := NewServeMuxMode(mode)
+		mux.Handle("/dir/", patternHandler())
+		mux.Handle("/tree/+", patternHandler())
+		mux.HandleMethod("POST", "/post/", patternHandler())
+		mux.Handle("/file", patternHandler())
+		mux.Handle("api.example.org/host/", patternHandler())
+		muxes = append(muxes, mux)
+	}
+	patterns := NewServeMuxMode(ModeRedirect | ModePatterns)
+	patterns.Handle("/dir/{$}", patternHandler())
+	patterns.Handle("/tree/", patternHandler())
+	patterns.Handle("POST /post/", patternHandler())
+	patterns.Handle("/file", patternHandler())
+	patterns.Handle("api.example.org/host/", patternHandler())
+	for _, mux := range append(muxes, patterns) {
+		for _, test := range []struct {
+			method, target string
+			code           int
+			location       string
+		}{
+			{"GET", "/dir", 301, "/dir/"},
+			{"GET", "/dir/", 200, ""},
+			{"GET", "/tree?a=1&b=2", 301, "/tree/?a=1&b=2"},
+			{"GET", "/tree/leaf", 200, ""},
+			{"GET", "/post", 301, "/post/"},
+			{"GET", "/post/", 405, ""},
+			{"GET", "/file", 200, ""},
+			{"GET", "/file/", 404, ""},
+			{"GET", "/other", 404, ""},
+			{"GET", "/dir/../file?q", 301, "/file?q"},
+			{"GET", "//tree//leaf/./", 301, "/tree/leaf/"},
+			{"GET", "http://api.example.org/host", 301, "/host/"},
+			{"GET", "/host", 404, ""},
+			{"CONNECT", "/dir", 404, ""},
+			{"OPTIONS", "*", 404, ""},
+		} {
+			r := httptest.NewRequest(test.method, test.target, nil)
+			if test.method == "CONNECT" {
+				r.URL.Path = test.target
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != test.code || w.Header().Get("Location") != test.location {
+				t.Fatalf("%s %s served %d Location '%s', expected %d Location '%s'", test.method, test.target,
+					w.Code, w.Header().Get("Location"), test.code, test.location)
+			}
+		}
+	}
+}
+
+func TestRedirectFallthrough(t *testing.T) {
+	mux := NewServeMuxMode(ModeRedirect | ModeFallthrough)
+	mux.Handle("/+", patternHandler())
+	mux.HandleFunc("/files/+", declineHandler)
+	for _, test := range []struct {
+		target   string
+		code     int
+		location string
+	}{
+		{"/files/../skip", 301, "/skip"},
+		{"/files//a", 301, "/files/a"},
+		{"/files/skip", 200, ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.code || w.Header().Get("Location") != test.location {
+			t.Fatalf("GET %s served %d Location '%s', expected %d Location '%s'", test.target,
+				w.Code, w.Header().Get("Location"), test.code, test.location)
+		}
+	}
+}
+
+func TestRedirectDisabled(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/dir/", patternHandler())
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/dir", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("ServeHTTP without ModeRedirect served %d", w.Code)
+	}
+}
@@ -9,43 +9,161 @@ package servemux
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"sync"
 
 	"github.com/vedranvuk/varouter"
 )
 
-// ServeMux is a serve mux that is API identical to http.ServeMux
-// but is using varouter internally.
+// Mode is a set of ServeMux behaviour flags.
+type Mode int
+
+const (
+	// ModePatterns makes ServeMux accept Go 1.22 net/http ServeMux patterns
+	// instead of varouter templates, i.e. "GET /items/{id}", "example.com/",
+	// "/files/{path...}" or "/{$}".
+	//
+	// Patterns are matched following the precedence rules of http.ServeMux:
+	// the most specific pattern matching a request wins and a pattern with a
+	// host takes precedence over a pattern without. Registering a pattern
+	// that conflicts with a registered pattern panics. Wildcard values are
+	// available to handlers served by ServeHTTP using Request.PathValue.
+	ModePatterns Mode = 1 << iota
+
+	// ModeFallthrough makes ServeHTTP serve a request by handlers of all
+	// matched templates, in reverse order of templates returned by
+	// Varouter.Match, until a handler serves it without calling Next.
+	// Templates with a host are tried before templates without one. If all
+	// handlers call Next the request is answered with "404 page not found".
+	// Middleware registered with Use is applied once and sees the pattern of
+	// the first handler.
+	//
+	// ModeFallthrough has no effect in ModePatterns.
+	ModeFallthrough
+
+	// ModeCascade makes handlers registered with Handle for prefix templates,
+	// such as "/admin/+", wrap handlers of templates they match, in order of
+	// template depth, the shallowest being the outermost. I.e. handlers of
+	// "/+" and "/admin/+" wrap the handler of "/admin/users" in that order.
+	// A wrapping handler serves the wrapped handler by calling Cascade.
+	//
+	// Handlers wrap handlers registered with the same host only. Handlers
+	// registered for a method and mounted ServeMux do not wrap handlers.
+	// ModeCascade has no effect in ModePatterns and cannot be combined with
+	// ModeFallthrough.
+	ModeCascade
+
+	// ModeRedirect makes ServeMux redirect requests to canonical paths as
+	// http.ServeMux does, preserving the query. A request whose path
+	// contains "." or ".." elements or repeated separators is redirected to
+	// the cleaned path. A request for "/dir" matching no pattern is
+	// redirected to "/dir/" if "/dir/" matches a pattern, such as "/dir/" or
+	// "/dir/+". Redirects are answered with "301 Moved Permanently".
+	//
+	// CONNECT requests, "OPTIONS *" requests and requests served by a
+	// mounted ServeMux are not redirected. In ModeFallthrough paths are
+	// cleaned before matched handlers are tried.
+	ModeRedirect
+)
+
+// ServeMux is a serve mux that is API identical to http.ServeMux, except for
+// Handler which also returns the request to serve, but is using varouter
+// internally.
 // The behaviour also mirrors Varouter's behaviour.
 //
 // Additionally, it stores any parsed Placeholders in a Placeholder map in the
-// request context which is accessible via Placeholders helper function.
+// request context which is accessible via Placeholders helper function and
+// sets them as request path values accessible via Request.PathValue.
 type ServeMux struct {
-	mu sync.Mutex
-	m  map[string]http.Handler
-	r  *varouter.Varouter
+	mu    sync.RWMutex
+	mode  Mode
+	table // table holds routes without a host.
+
+	hosts      *varouter.Varouter // hosts, if not nil, matches host templates.
+	hostTables map[string]*table  // hostTables maps host templates to their routes.
+
+	middleware []Middleware // middleware is applied by ServeHTTP.
+	notFound   http.Handler // notFound is the "page not found" handler wrapped in middleware.
+	dispatch   http.Handler // dispatch serves fallthrough candidates wrapped in middleware.
+}
+
+// table is a set of routes matched by path.
+type table struct {
+	r      *varouter.Varouter  // r matches path templates.
+	routes map[string][]*route // routes maps path templates to their routes.
+	byPath []*route            // byPath are routes sorted by path in ModeCascade.
+}
+
+// newTable returns a new *table using r.
+func newTable(r *varouter.Varouter) table {
+	return table{r: r, routes: make(map[string][]*route)}
+}
+
+// route is a handler registered for a template.
+type route struct {
+	// pattern is the pattern the handler was registered with.
+	pattern string
+	// parsed is the parsed pattern in ModePatterns, nil otherwise.
+	parsed *pattern
+	// method, if not empty, is the method the handler is registered for.
+	method string
+	// handler is the registered handler.
+	handler http.Handler
+	// path is the path described by the template in ModeCascade.
+	path string
+	// depth is the number of named elements of the template in ModeCascade.
+	depth int
+	// layer specifies if handler wraps handlers of templates the template
+	// matches in ModeCascade.
+	layer bool
+	// layers are routes of layers wrapping handler in ModeCascade, the
+	// outermost first.
+	layers []*route
+	// layered is handler wrapped in handlers of layers.
+	layered http.Handler
+	// served is layered wrapped in middleware registered with Use.
+	served http.Handler
 }
 
 // NewServeMux returns a new ServeMux instance.
-func NewServeMux() *ServeMux {
-	return &ServeMux{
-		mu: sync.Mutex{},
-		m:  make(map[string]http.Handler),
-		r:  varouter.New(),
+func NewServeMux() *ServeMux { return NewServeMuxMode(0) }
+
+// NewServeMuxMode returns a new ServeMux instance with the specified mode.
+// It panics if mode combines ModeCascade and ModeFallthrough.
+func NewServeMuxMode(mode Mode) *ServeMux {
+	if mode&ModeCascade != 0 && mode&ModeFallthrough != 0 {
+		panic("ModeCascade cannot be combined with ModeFallthrough")
 	}
+	var mux = &ServeMux{mode: mode}
+	if mode&ModePatterns != 0 {
+		mux.table = newTable(newPatternRouter())
+	} else {
+		mux.table = newTable(varouter.New())
+	}
+	mux.wrap()
+	return mux
 }
 
 // Handle registers the handler for the given pattern.
 // If a handler already exists for pattern, Handle panics.
+//
+// The handler is served for any method not registered for pattern using
+// HandleMethod.
+//
+// A pattern may be prefixed with a host template whose labels are
+// separated by dots, i.e. ":tenant.example.com/+". Host labels may be
+// variables, wildcards or, as the first label, the prefix character which
+// matches any number of subdomains, i.e. "+.example.com/". Host variables
+// are merged with path variables, which take precedence. Hosts are matched
+// case insensitively, without the port. Host templates follow Varouter
+// registration rules, i.e. ":tenant.example.com" and "api.example.com"
+// cannot both be registered. A request whose host and path do not match a
+// pattern with a host is matched against patterns without one.
 func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
-	mux.mu.Lock()
-	defer mux.mu.Unlock()
-
-	if err := mux.r.Register(pattern); err != nil {
-		panic(err)
-	}
-	mux.m[pattern] = handler
+	mux.HandleMethod("", pattern, handler)
 }
 
 // HandleFunc registers the handler function for the given pattern.
@@ -53,12 +171,52 @@ func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter
 	mux.Handle(pattern, http.HandlerFunc(handler))
 }
 
+// registerPattern registers the handler for a Go 1.22 net/http pattern.
+func (mux *ServeMux) registerPattern(s string, handler http.Handler) error {
+	var p, err = parsePattern(s)
+	if err != nil {
+		return err
+	}
+	for _, routes := range mux.routes {
+		for _, route := range routes {
+			if p.conflictsWith(route.parsed) {
+				return fmt.Errorf("pattern %q conflicts with pattern %q", p.str, route.pattern)
+			}
+		}
+	}
+	var template = p.template()
+	// Patterns that differ only in wildcard names, {$} and methods share a
+	// template.
+	if err = mux.r.Register(template); err != nil && !errors.Is(err, varouter.ErrDuplicate) {
+		return err
+	}
+	if _, exists := mux.routes[template]; err != nil && !exists {
+		return fmt.Errorf("pattern %q cannot be registered: %w", p.str, err)
+	}
+	var rt = &route{pattern: s, parsed: p, method: p.method, handler: handler}
+	mux.routes[template] = append(mux.routes[template], rt)
+	mux.wrapRoute(rt)
+	return nil
+}
+
 // placeholderKey is a type of context key used to store Placeholder map.
 type placeholderKey struct{ key string }
 
 // placeholders is the Placeholders context key.
 var placeholders = placeholderKey{"varouter/servemux/placeholders"}
 
+// patternKey is the Pattern context key.
+var patternKey = placeholderKey{"varouter/servemux/pattern"}
+
+// Pattern returns the pattern of the handler serving r, as registered,
+// including any group prefix and path prefix stripped by mounts. It returns
+// an empty string if r was not returned by Handler or served by ServeHTTP or
+// no pattern matched r.
+func Pattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(patternKey).(string)
+	return pattern
+}
+
 // Placeholders is a helper method that retrieves Placeholders map from the
 // context of a *http.Request. If no Placeholder map was stored in the request
 // a nil Placeholders map is returned.
@@ -70,27 +228,217 @@ func Placeholders(r *http.Request) varouter.Vars {
 }
 
 // Handler returns the handler to use for the given request,
-// consulting r.URL.Path. It always returns a non-nil handler.
+// consulting r.URL.Path, and the request to pass to it. It always returns a
+// non-nil handler.
 //
 // If there is no registered handler that applies to the request,
 // Handler returns a ``page not found'' handler and an empty pattern.
-func (mux *ServeMux) Handler(r *http.Request) (h http.Handler, pattern string) {
-	mux.mu.Lock()
-	defer mux.mu.Unlock()
+//
+// Unlike http.ServeMux, Handler returns a request carrying variables parsed
+// from r.URL.Path. The pattern is stored in the returned request context and
+// available through Pattern. Variables are stored as a Placeholders map in
+// the returned request context and set as its path values available through
+// Request.PathValue. If no pattern matched r is returned.
+func (mux *ServeMux) Handler(r *http.Request) (h http.Handler, pattern string, req *http.Request) {
+	var vars varouter.Vars
+	mux.mu.RLock()
+	h, pattern, vars = mux.handler(r, nil)
+	mux.mu.RUnlock()
+	return h, pattern, mux.request(r, pattern, vars)
+}
 
-	templates, params, matched := mux.r.Match(r.URL.Path)
+// request returns a request carrying the pattern and variables matched by r
+// or r if pattern is empty.
+func (mux *ServeMux) request(r *http.Request, pattern string, vars varouter.Vars) (req *http.Request) {
+	if pattern == "" {
+		return r
+	}
+	var prefixed = pattern
+	if prefix := mountedPrefix(r); prefix != "" {
+		prefixed = mux.prefixPattern(prefix, pattern)
+		vars = mergeVars(Placeholders(r), vars)
+	}
+	var ctx = context.WithValue(r.Context(), patternKey, prefixed)
+	if len(vars) == 0 {
+		return r.WithContext(ctx)
+	}
+	// Clone r so that setting path values does not modify its path values.
+	req = r.Clone(context.WithValue(ctx, placeholders, vars))
+	for name, value := range vars {
+		req.SetPathValue(name, value)
+	}
+	return req
+}
+
+// handler returns the handler to use for the given request wrapped in mw,
+// its pattern and variables parsed from r.URL.Path. Caller must hold the read
+// lock.
+func (mux *ServeMux) handler(r *http.Request, mw []Middleware) (h http.Handler, pattern string, vars varouter.Vars) {
+	var redirect = mux.redirects(r)
+	if redirect {
+		if h, ok := cleanRedirect(r); ok {
+			return chain(mw, h), "", nil
+		}
+	}
+	var found bool
+	if h, pattern, vars, found = mux.lookup(r, mw); !found && redirect {
+		if h, ok := mux.slashRedirect(r); ok {
+			return chain(mw, h), "", nil
+		}
+	}
+	return
+}
+
+// lookup returns the handler to use for the given request wrapped in mw, its
+// pattern and variables parsed from r.URL.Path and a truth if r.URL.Path
+// matched a pattern, possibly registered for other methods only.
+func (mux *ServeMux) lookup(r *http.Request, mw []Middleware) (h http.Handler, pattern string, vars varouter.Vars, found bool) {
+	if mux.mode&ModePatterns != 0 {
+		var route, values, allow = mux.matchPattern(r)
+		if route == nil {
+			if allow != nil {
+				return chain(mw, methodHandler(r.Method, allow)), "", nil, true
+			}
+			return mux.notFoundHandler(mw), "", nil, false
+		}
+		return route.handlerIn(mw), route.pattern, route.parsed.vars(values), true
+	}
+	// Answer with allowed methods only if no matched template of any table
+	// has a handler serving the request method.
+	var allow http.Handler
+	if mux.hosts != nil {
+		var hosttemplates, hostvars, _ = mux.hosts.Match(hostTemplate(stripHostPort(r.Host)))
+		for i := len(hosttemplates) - 1; i >= 0; i-- {
+			var route, vars, a = mux.hostTables[hosttemplates[i]].match(r)
+			if route != nil {
+				return route.handlerIn(mw), route.pattern, mergeVars(hostvars, vars), true
+			}
+			if allow == nil {
+				allow = a
+			}
+		}
+	}
+	var route, pathvars, a = mux.table.match(r)
+	if route != nil {
+		return route.handlerIn(mw), route.pattern, pathvars, true
+	}
+	if allow == nil {
+		allow = a
+	}
+	if allow != nil {
+		return chain(mw, allow), "", nil, true
+	}
+	return mux.notFoundHandler(mw), "", nil, false
+}
+
+// notFoundHandler returns the "page not found" handler wrapped in mw, which
+// is either nil or the middleware of mux.
+func (mux *ServeMux) notFoundHandler(mw []Middleware) http.Handler {
+	if mw == nil {
+		return http.NotFoundHandler()
+	}
+	return mux.notFound
+}
+
+// handlerIn returns the layered handler of rt wrapped in mw, which is either
+// nil or the middleware of the ServeMux rt is registered with.
+func (rt *route) handlerIn(mw []Middleware) http.Handler {
+	if mw == nil {
+		return rt.layered
+	}
+	return rt.served
+}
+
+// match returns the route of the most specific template matching r.URL.Path
+// with a handler serving r.Method and variables parsed from r.URL.Path. If
+// templates match but none has a handler serving r.Method, match returns a
+// nil route and a handler answering with the methods they allow.
+func (t *table) match(r *http.Request) (rt *route, vars varouter.Vars, allow http.Handler) {
+	var templates []string
+	var matched bool
+	if templates, vars, matched = t.r.Match(r.URL.Path); !matched {
+		return nil, nil, nil
+	}
+	var mismatched []*route
+	for i := len(templates) - 1; i >= 0; i-- {
+		var routes = t.routes[templates[i]]
+		if rt = methodRoute(routes, r.Method); rt != nil {
+			return rt, vars, nil
+		}
+		mismatched = append(mismatched, routes...)
+	}
+	if mismatched == nil {
+		return nil, nil, nil
+	}
+	// Layers are handlers for any method, so no layer matched r.
+	return nil, nil, methodHandler(r.Method, allowedMethods(mismatched))
+}
+
+// matchPattern returns the route whose pattern takes precedence among
+// patterns matching r and its wildcard values or nil if none match. If
+// patterns match r except for the method, methods they allow are returned.
+func (mux *ServeMux) matchPattern(r *http.Request) (best *route, values, allow []string) {
+	var templates, _, matched = mux.r.Match(r.URL.Path)
 	if !matched {
-		return http.NotFoundHandler(), ""
+		return nil, nil, nil
+	}
+	var host = stripHostPort(r.Host)
+	var mismatched []*route
+	for _, template := range templates {
+		for _, route := range mux.routes[template] {
+			if route.parsed.host != "" && route.parsed.host != host {
+				continue
+			}
+			var vals, ok = route.parsed.match(r.URL.Path)
+			if !ok {
+				continue
+			}
+			if !route.matchMethod(r.Method) {
+				mismatched = append(mismatched, route)
+				continue
+			}
+			if best != nil {
+				// A pattern with a host takes precedence, otherwise the
+				// more specific pattern does.
+				if best.parsed.host != route.parsed.host {
+					if best.parsed.host != "" {
+						continue
+					}
+				} else if route.parsed.compare(best.parsed) != moreSpecific {
+					continue
+				}
+			}
+			best, values = route, vals
+		}
+	}
+	if best == nil && mismatched != nil {
+		allow = allowedMethods(mismatched)
 	}
-	r = r.WithContext(context.WithValue(r.Context(), placeholders, params))
-	pattern = templates[len(templates)-1]
-	h = mux.m[pattern]
 	return
 }
 
 // ServeHTTP dispatches the request to the handler whose
-// pattern most closely matches the request URL.
+// pattern most closely matches the request URL, wrapped in middleware
+// registered with Use.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handler, _ := mux.Handler(r)
+	var handler http.Handler
+	mux.mu.RLock()
+	if mux.mode&ModeFallthrough != 0 && mux.mode&ModePatterns == 0 {
+		handler, r = mux.fallthroughHandler(r)
+	} else {
+		var pattern string
+		var vars varouter.Vars
+		handler, pattern, vars = mux.handler(r, mux.middleware)
+		r = mux.request(r, pattern, vars)
+	}
+	mux.mu.RUnlock()
 	handler.ServeHTTP(w, r)
 }
+
+// stripHostPort returns h without any trailing ":<port>".
+func stripHostPort(h string) string {
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		return host
+	}
+	return h
+}
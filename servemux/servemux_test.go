@@ -3,3 +3,90 @@
 // license that can be found in the LICENSE file.
 
 package servemux
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// varsHandler responds with the pattern and Placeholders and path values of
+// the specified variable names.
+func varsHandler(pattern string, names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pattern)
+		vars := Placeholders(r)
+		for _, name := range names {
+			fmt.Fprintf(w, " %s=%s,%s", name, vars[name], r.PathValue(name))
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/+", varsHandler("/+"))
+	mux.Handle("/home/:user", varsHandler("/home/:user", "user"))
+	mux.Handle("/home/:user/.config/:application", varsHandler("/home/:user/.config/:application", "user", "application"))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	for _, test := range []struct {
+		path, expected string
+	}{
+		{"/", "/+"},
+		{"/etc/passwd", "/+"},
+		{"/home/vedran", "/home/:user user=vedran,vedran"},
+		{"/home/vedran/.config/myapp", "/home/:user/.config/:application user=vedran,vedran application=myapp,myapp"},
+	} {
+		resp, err := srv.Client().Get(srv.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != test.expected {
+			t.Fatalf("GET %s served '%s', expected '%s'", test.path, body, test.expected)
+		}
+	}
+}
+
+func TestServeHTTPNotFound(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/home/:user", varsHandler("/home/:user", "user"))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/etc/passwd", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("ServeHTTP served %d, expected %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	for _, mux := range []*ServeMux{NewServeMux(), NewServeMuxMode(ModePatterns)} {
+		pattern := "/home/:user"
+		if mux.mode&ModePatterns != 0 {
+			pattern = "/home/{user}"
+		}
+		mux.Handle(pattern, varsHandler(pattern, "user"))
+		r := httptest.NewRequest("GET", "/home/vedran", nil)
+		r.SetPathValue("user", "original")
+		h, p, req := mux.Handler(r)
+		if h == nil || p != pattern {
+			t.Fatalf("Handler returned pattern '%s', expected '%s'", p, pattern)
+		}
+		if Placeholders(req)["user"] != "vedran" || req.PathValue("user") != "vedran" {
+			t.Fatalf("Handler did not deliver variables: '%v'", Placeholders(req))
+		}
+		if Placeholders(r) != nil || r.PathValue("user") != "original" {
+			t.Fatal("Handler modified the request.")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Body.String() != pattern+" user=vedran,vedran" {
+			t.Fatalf("Handler served '%s'", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SnapshotVersion is the version of the snapshot format produced by
+// MarshalBinary and MarshalJSON. Snapshots of other versions are rejected.
+const SnapshotVersion = 1
+
+// snapshotMagic is the header of a binary snapshot.
+const snapshotMagic = "VRTR"
+
+// snapshot is a serializable Varouter state.
+type snapshot struct {
+	Version    int              `json:"version"`
+	Tokens     snapshotTokens   `json:"tokens"`
+	Backend    Backend          `json:"backend"`
+	Compressed bool             `json:"compressed,omitempty"`
+	Count      int              `json:"count"`
+	Root       *snapshotElement `json:"root"`
+}
+
+// snapshotTokens are the configured token characters.
+type snapshotTokens struct {
+	Override     string `json:"override"`
+	Exclusion    string `json:"exclusion"`
+	Separator    string `json:"separator"`
+	Variable     string `json:"variable"`
+	Prefix       string `json:"prefix"`
+	WildcardOne  string `json:"wildcardOne"`
+	WildcardMany string `json:"wildcardMany"`
+}
+
+// snapshotElement is a serializable element used by JSON snapshots.
+type snapshotElement struct {
+	Name        string             `json:"name,omitempty"`
+	Tail        string             `json:"tail,omitempty"`
+	Template    string             `json:"template,omitempty"`
+	HasVariable string             `json:"hasVariable,omitempty"`
+	Priority    int                `json:"priority,omitempty"`
+	IsPrefix    bool               `json:"isPrefix,omitempty"`
+	IsOverride  bool               `json:"isOverride,omitempty"`
+	IsExclusion bool               `json:"isExclusion,omitempty"`
+	IsWildcard  bool               `json:"isWildcard,omitempty"`
+	Subs        []*snapshotElement `json:"subs,omitempty"`
+}
+
+// Flags of a binary snapshot element.
+const (
+	flagPrefix = 1 << iota
+	flagOverride
+	flagExclusion
+	flagWildcard
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// It encodes the registered template tree and configuration into a snapshot
+// which UnmarshalBinary can restore without registering templates again.
+// The snapshot starts with a header carrying SnapshotVersion. Elements are
+// encoded in name order so equal trees produce equal snapshots.
+//
+// Match cache settings and routes registered with RegisterRoute are not part
+// of a snapshot.
+func (vr *Varouter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	writeUvarint(&buf, SnapshotVersion)
+	buf.Write([]byte{vr.override, vr.exclusion, vr.separator, vr.variable,
+		vr.prefix, vr.wildcardone, vr.wildcardmany})
+	writeUvarint(&buf, uint64(vr.backend))
+	if vr.compressed {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	writeUvarint(&buf, uint64(vr.count))
+	writeElement(&buf, vr.root)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It replaces the registered templates and configuration of vr with those
+// of a snapshot produced by MarshalBinary. Snapshots of a version other than
+// SnapshotVersion are rejected with an ErrSnapshot. On error vr is unchanged.
+func (vr *Varouter) UnmarshalBinary(data []byte) (err error) {
+	var r = bytes.NewReader(data)
+	var magic = make([]byte, len(snapshotMagic))
+	if _, err = io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return fmt.Errorf("%w: invalid header", ErrSnapshot)
+	}
+	var version, backend, count uint64
+	if version, err = binary.ReadUvarint(r); err != nil {
+		return fmt.Errorf("%w: invalid header", ErrSnapshot)
+	}
+	if version != SnapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrSnapshot, version)
+	}
+	var restored Varouter
+	for _, p := range []*byte{&restored.override, &restored.exclusion, &restored.separator,
+		&restored.variable, &restored.prefix, &restored.wildcardone, &restored.wildcardmany} {
+		if *p, err = r.ReadByte(); err != nil {
+			return fmt.Errorf("%w: invalid tokens", ErrSnapshot)
+		}
+	}
+	if backend, err = binary.ReadUvarint(r); err != nil {
+		return fmt.Errorf("%w: invalid backend", ErrSnapshot)
+	}
+	restored.backend = Backend(backend)
+	var compressed byte
+	if compressed, err = r.ReadByte(); err != nil {
+		return fmt.Errorf("%w: invalid header", ErrSnapshot)
+	}
+	restored.compressed = compressed != 0
+	if count, err = binary.ReadUvarint(r); err != nil {
+		return fmt.Errorf("%w: invalid count", ErrSnapshot)
+	}
+	restored.count = int(count)
+	// Element strings are sliced from a single copy of data.
+	var text = string(data)
+	if restored.root, err = restored.readElement(r, &text); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: trailing data", ErrSnapshot)
+	}
+	vr.replace(&restored)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// It encodes the same snapshot as MarshalBinary as JSON.
+func (vr *Varouter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&snapshot{
+		Version: SnapshotVersion,
+		Tokens: snapshotTokens{
+			Override:     string(vr.override),
+			Exclusion:    string(vr.exclusion),
+			Separator:    string(vr.separator),
+			Variable:     string(vr.variable),
+			Prefix:       string(vr.prefix),
+			WildcardOne:  string(vr.wildcardone),
+			WildcardMany: string(vr.wildcardmany),
+		},
+		Backend:    vr.backend,
+		Compressed: vr.compressed,
+		Count:      vr.count,
+		Root:       snapshotOf(vr.root),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// It restores a snapshot produced by MarshalJSON.
+// See UnmarshalBinary for details.
+func (vr *Varouter) UnmarshalJSON(data []byte) (err error) {
+	var s snapshot
+	if err = json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshot, err)
+	}
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrSnapshot, s.Version)
+	}
+	var restored = Varouter{
+		count:      s.Count,
+		backend:    s.Backend,
+		compressed: s.Compressed,
+	}
+	for _, t := range []struct {
+		token string
+		p     *byte
+	}{
+		{s.Tokens.Override, &restored.override},
+		{s.Tokens.Exclusion, &restored.exclusion},
+		{s.Tokens.Separator, &restored.separator},
+		{s.Tokens.Variable, &restored.variable},
+		{s.Tokens.Prefix, &restored.prefix},
+		{s.Tokens.WildcardOne, &restored.wildcardone},
+		{s.Tokens.WildcardMany, &restored.wildcardmany},
+	} {
+		if len(t.token) != 1 {
+			return fmt.Errorf("%w: invalid token '%s'", ErrSnapshot, t.token)
+		}
+		*t.p = t.token[0]
+	}
+	if s.Root == nil {
+		return fmt.Errorf("%w: missing root", ErrSnapshot)
+	}
+	if restored.root, err = restored.restoreElement(s.Root); err != nil {
+		return err
+	}
+	vr.replace(&restored)
+	return nil
+}
+
+// replace replaces the state of vr with the restored state, retaining and
+// purging the cache of vr.
+func (vr *Varouter) replace(restored *Varouter) {
+	restored.cache = vr.cache
+	*vr = *restored
+	if vr.cache != nil {
+		vr.cache.purge()
+	}
+}
+
+// snapshotOf returns a snapshot of the element tree of e, subs sorted by name.
+func snapshotOf(e *element) *snapshotElement {
+	var s = &snapshotElement{
+		Name:        e.name,
+		Tail:        e.tail,
+		Template:    e.template,
+		HasVariable: e.hasvariable,
+		Priority:    e.priority,
+		IsPrefix:    e.isprefix,
+		IsOverride:  e.isoverride,
+		IsExclusion: e.isexclusion,
+		IsWildcard:  e.iswildcard,
+	}
+	for _, sub := range sortedSubs(e) {
+		s.Subs = append(s.Subs, snapshotOf(sub))
+	}
+	return s
+}
+
+// restoreElement returns an element tree restored from s.
+func (vr *Varouter) restoreElement(s *snapshotElement) (e *element, err error) {
+	e = newElement()
+	e.name = s.Name
+	e.tail = s.Tail
+	e.template = s.Template
+	e.hasvariable = s.HasVariable
+	e.priority = s.Priority
+	e.isprefix = s.IsPrefix
+	e.isoverride = s.IsOverride
+	e.isexclusion = s.IsExclusion
+	e.iswildcard = s.IsWildcard
+	vr.restoreTemplate(e)
+	var sub *element
+	for _, ss := range s.Subs {
+		if ss == nil {
+			return nil, fmt.Errorf("%w: missing element", ErrSnapshot)
+		}
+		if sub, err = vr.restoreElement(ss); err != nil {
+			return nil, err
+		}
+		if err = restoreSub(e, sub); err != nil {
+			return nil, err
+		}
+	}
+	return e, checkVariable(e)
+}
+
+// restoreTemplate registers priority and override state of a restored
+// template element e with vr.
+func (vr *Varouter) restoreTemplate(e *element) {
+	if e.template == "" {
+		return
+	}
+	if e.priority != 0 {
+		if vr.priorities == nil {
+			vr.priorities = make(map[string]int)
+		}
+		vr.priorities[e.template] = e.priority
+	}
+	if e.isoverride || e.isexclusion {
+		vr.overrides++
+	}
+}
+
+// restoreSub adds a restored sub element to e and its indexes.
+func restoreSub(e, sub *element) error {
+	if _, exists := e.subs[sub.name]; exists {
+		return fmt.Errorf("%w: duplicate element '%s'", ErrSnapshot, sub.name)
+	}
+	e.subs[sub.name] = sub
+	if sub.iswildcard {
+		e.haswildcards = true
+		e.wildcards = insertWildcard(e.wildcards, sub)
+	}
+	if sub.isprefix {
+		e.hasprefixes = true
+		if e.prefixes == nil {
+			e.prefixes = &prefixNode{}
+		}
+		e.prefixes.insert(sub)
+	}
+	return nil
+}
+
+// checkVariable returns an error if the variable of e is not its sub.
+func checkVariable(e *element) error {
+	if _, exists := e.subs[e.hasvariable]; e.hasvariable != "" && !exists {
+		return fmt.Errorf("%w: missing variable element '%s'", ErrSnapshot, e.hasvariable)
+	}
+	return nil
+}
+
+// sortedSubs returns subs of e sorted by name.
+func sortedSubs(e *element) []*element {
+	if e.literals != nil {
+		return e.literals
+	}
+	var subs = make([]*element, 0, len(e.subs))
+	for _, sub := range e.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].name < subs[j].name })
+	return subs
+}
+
+// writeUvarint writes an uvarint to buf.
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// writeString writes a length prefixed string to buf.
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// writeElement writes the element tree of e to buf, subs sorted by name.
+func writeElement(buf *bytes.Buffer, e *element) {
+	writeString(buf, e.name)
+	writeString(buf, e.tail)
+	writeString(buf, e.template)
+	writeString(buf, e.hasvariable)
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], int64(e.priority))])
+	var flags byte
+	if e.isprefix {
+		flags |= flagPrefix
+	}
+	if e.isoverride {
+		flags |= flagOverride
+	}
+	if e.isexclusion {
+		flags |= flagExclusion
+	}
+	if e.iswildcard {
+		flags |= flagWildcard
+	}
+	buf.WriteByte(flags)
+	writeUvarint(buf, uint64(len(e.subs)))
+	for _, sub := range sortedSubs(e) {
+		writeElement(buf, sub)
+	}
+}
+
+// readString reads a length prefixed string from r and returns it as a
+// substring of text which holds the data r reads.
+func readString(r *bytes.Reader, text *string) (string, error) {
+	var l, err = binary.ReadUvarint(r)
+	if err != nil || l > uint64(r.Len()) {
+		return "", fmt.Errorf("%w: invalid string", ErrSnapshot)
+	}
+	var offset = len(*text) - r.Len()
+	r.Seek(int64(l), io.SeekCurrent)
+	return (*text)[offset : offset+int(l)], nil
+}
+
+// readElement reads an element tree from r which reads text.
+func (vr *Varouter) readElement(r *bytes.Reader, text *string) (e *element, err error) {
+	e = newElement()
+	for _, p := range [...]*string{&e.name, &e.tail, &e.template, &e.hasvariable} {
+		if *p, err = readString(r, text); err != nil {
+			return nil, err
+		}
+	}
+	var priority int64
+	if priority, err = binary.ReadVarint(r); err != nil {
+		return nil, fmt.Errorf("%w: invalid priority", ErrSnapshot)
+	}
+	e.priority = int(priority)
+	var flags byte
+	if flags, err = r.ReadByte(); err != nil {
+		return nil, fmt.Errorf("%w: invalid flags", ErrSnapshot)
+	}
+	e.isprefix = flags&flagPrefix != 0
+	e.isoverride = flags&flagOverride != 0
+	e.isexclusion = flags&flagExclusion != 0
+	e.iswildcard = flags&flagWildcard != 0
+	vr.restoreTemplate(e)
+	var n uint64
+	if n, err = binary.ReadUvarint(r); err != nil || n > uint64(r.Len()) {
+		return nil, fmt.Errorf("%w: invalid element count", ErrSnapshot)
+	}
+	var sub *element
+	for ; n > 0; n-- {
+		if sub, err = vr.readElement(r, text); err != nil {
+			return nil, err
+		}
+		if err = restoreSub(e, sub); err != nil {
+			return nil, err
+		}
+	}
+	return e, checkVariable(e)
+}
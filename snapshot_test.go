@@ -0,0 +1,134 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newSnapshotTestVarouter returns a Varouter with various templates.
+func newSnapshotTestVarouter(t *testing.T) *Varouter {
+	vr := NewVarouter(false, '#', '/', '$', '%', '?', '*')
+	vr.SetExclusion('~')
+	for _, template := range []string{
+		"/%",
+		"/home/$user/%",
+		"#/home/$user/.config",
+		"~/home/$user/.cache",
+		"/etc/h*s",
+		"/usr/local/share/doc",
+	} {
+		if err := vr.RegisterPriority(template, len(template)%3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vr
+}
+
+// compareMatches fails t if a and b match paths differently.
+func compareMatches(t *testing.T, a, b *Varouter) {
+	for _, path := range []string{
+		"/",
+		"/home/vedran/.config",
+		"/home/vedran/.cache",
+		"/home/vedran/Go",
+		"/etc/hosts",
+		"/usr/local/share/doc",
+		"/usr/local/share",
+	} {
+		am, av, aok := a.Match(path)
+		bm, bv, bok := b.Match(path)
+		if fmt.Sprint(am, av, aok) != fmt.Sprint(bm, bv, bok) {
+			t.Fatalf("Snapshot match of '%s' differs: '%v %v %t' != '%v %v %t'", path, am, av, aok, bm, bv, bok)
+		}
+	}
+}
+
+func TestSnapshotBinary(t *testing.T) {
+	vr := newSnapshotTestVarouter(t)
+	for _, compress := range []bool{false, true} {
+		if compress {
+			vr.SetBackend(BackendRadix)
+			vr.Freeze()
+		}
+		data, err := vr.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := New()
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		compareMatches(t, vr, loaded)
+		if loaded.compressed != compress || loaded.NumTemplates() != vr.NumTemplates() {
+			t.Fatal("Snapshot state differs.")
+		}
+		again, err := loaded.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, again) {
+			t.Fatal("Snapshot is not deterministic.")
+		}
+		if err := loaded.Register("/home/$user/.local"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	vr := newSnapshotTestVarouter(t)
+	data, err := vr.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := New()
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	compareMatches(t, vr, loaded)
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	vr := newSnapshotTestVarouter(t)
+	data, _ := vr.MarshalBinary()
+	data[len(snapshotMagic)] = SnapshotVersion + 1
+	if err := New().UnmarshalBinary(data); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("Failed rejecting binary snapshot version: %v", err)
+	}
+	if err := New().UnmarshalBinary(data[:10]); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("Failed rejecting truncated binary snapshot: %v", err)
+	}
+	if err := New().UnmarshalJSON([]byte(`{"version":2,"root":{}}`)); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("Failed rejecting JSON snapshot version: %v", err)
+	}
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	vr := New()
+	data := makeBenchData(10000, 8, 8)
+	for _, template := range data.Templates {
+		vr.Register(template)
+	}
+	snapshot, _ := vr.MarshalBinary()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New().UnmarshalBinary(snapshot)
+	}
+}
+
+func BenchmarkRegisterAll(b *testing.B) {
+	data := makeBenchData(10000, 8, 8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vr := New()
+		for _, template := range data.Templates {
+			vr.Register(template)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package syntax translates route patterns between varouter templates and
+// pattern dialects of other routers.
+//
+// Variables that match a single path element translate to varouter
+// variables. Trailing variables that match the rest of a path translate to
+// varouter prefix templates and the variable name is lost; in the other
+// direction a prefix template binds the rest of the path to a variable named
+// "rest". A prefix variable, such as "/home/:user+", translates to the
+// variable followed by such a rest variable; the translated pattern does not
+// match a path that ends with the variable value. Override templates
+// translate to patterns of other routers without the override character as
+// those always select a single route. Constructs with no equivalent in the
+// target dialect, such as exclusions, wildcard patterns and regular
+// expressions, are reported with an *Error.
+package syntax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/vedranvuk/varouter"
+)
+
+var (
+	// ErrSyntax is the base syntax package error.
+	ErrSyntax = errors.New("syntax")
+	// ErrInvalid is returned when a pattern is invalid in its dialect.
+	ErrInvalid = fmt.Errorf("%w: invalid pattern", ErrSyntax)
+	// ErrUntranslatable is returned when a pattern contains a construct
+	// that has no equivalent in the target dialect.
+	ErrUntranslatable = fmt.Errorf("%w: untranslatable pattern", ErrSyntax)
+)
+
+// Error is returned when a pattern is invalid or cannot be translated.
+type Error struct {
+	// Pattern is the pattern being translated.
+	Pattern string
+	// Construct is the offending part of Pattern.
+	Construct string
+	// Err is ErrInvalid or ErrUntranslatable wrapped with a reason.
+	Err error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: '%s' in '%s'", e.Err, e.Construct, e.Pattern)
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Dialect is a route pattern syntax.
+type Dialect int
+
+const (
+	// Varouter are varouter templates using varouter.DefaultTokens,
+	// i.e. "/items/:id", "/static/+", "!/admin/+".
+	Varouter Dialect = iota
+	// Gorilla are gorilla/mux path templates, i.e. "/items/{id}",
+	// "/static/{rest:.*}".
+	Gorilla
+	// HTTPRouter are julienschmidt/httprouter paths, i.e. "/items/:id",
+	// "/static/*filepath".
+	HTTPRouter
+	// ServeMux are Go 1.22 net/http ServeMux path patterns, i.e.
+	// "/items/{id}", "/static/", "/static/{rest...}", "/{$}". Patterns with
+	// a method or a host are not translated.
+	ServeMux
+)
+
+// String implements fmt.Stringer.
+func (d Dialect) String() string {
+	switch d {
+	case Varouter:
+		return "varouter"
+	case Gorilla:
+		return "gorilla"
+	case HTTPRouter:
+		return "httprouter"
+	case ServeMux:
+		return "servemux"
+	}
+	return "unknown"
+}
+
+// restName is the name of the variable a prefix template translates to.
+const restName = "rest"
+
+// Translate translates a pattern from one dialect to another. If the pattern
+// is invalid or cannot be translated an *Error is returned.
+func Translate(pattern string, from, to Dialect) (string, error) {
+	var template, err = ToVarouter(pattern, from)
+	if err != nil {
+		return "", err
+	}
+	if pattern, err = FromVarouter(template, to); err != nil {
+		return "", err
+	}
+	return pattern, nil
+}
+
+// ToVarouter translates a pattern of the specified dialect to a varouter
+// template.
+func ToVarouter(pattern string, from Dialect) (string, error) {
+	var fail = func(err error, construct, reason string) (string, error) {
+		return "", &Error{pattern, construct, fmt.Errorf("%w: %s", err, reason)}
+	}
+	if from == Varouter {
+		if _, err := varouter.Parse(pattern, varouter.DefaultTokens); err != nil {
+			return fail(ErrInvalid, pattern, err.Error())
+		}
+		return pattern, nil
+	}
+	if pattern == "" || pattern[0] != '/' {
+		if from == ServeMux {
+			return fail(ErrUntranslatable, pattern, "methods and hosts are not supported")
+		}
+		return fail(ErrInvalid, pattern, "pattern must start with '/'")
+	}
+	var elements = strings.Split(pattern[1:], "/")
+	var sb strings.Builder
+	for i, elem := range elements {
+		var last = i == len(elements)-1
+		sb.WriteByte('/')
+		switch from {
+		case Gorilla:
+			if !strings.ContainsAny(elem, "{}") {
+				break
+			}
+			if elem[0] != '{' || closing(elem) != len(elem)-1 {
+				return fail(ErrUntranslatable, elem, "variables must span a whole path element")
+			}
+			var name, regexp = elem[1 : len(elem)-1], ""
+			if colon := strings.IndexByte(name, ':'); colon >= 0 {
+				name, regexp = name[:colon], name[colon+1:]
+			}
+			if name == "" {
+				return fail(ErrInvalid, elem, "empty variable name")
+			}
+			switch {
+			case regexp == "":
+				sb.WriteString(":" + name)
+			case regexp == ".*" && last:
+				sb.WriteByte('+')
+			default:
+				return fail(ErrUntranslatable, elem, "regular expressions are not supported")
+			}
+			continue
+		case HTTPRouter:
+			if !strings.ContainsAny(elem, ":*") {
+				break
+			}
+			if elem[0] != ':' && elem[0] != '*' || strings.ContainsAny(elem[1:], ":*") {
+				return fail(ErrUntranslatable, elem, "parameters must span a whole path element")
+			}
+			if len(elem) < 2 {
+				return fail(ErrInvalid, elem, "empty parameter name")
+			}
+			if elem[0] == ':' {
+				sb.WriteString(elem)
+				continue
+			}
+			if !last {
+				return fail(ErrInvalid, elem, "catch-all parameters must end the path")
+			}
+			sb.WriteByte('+')
+			continue
+		case ServeMux:
+			if last && elem == "" {
+				// Trailing slash matches a subtree.
+				sb.WriteByte('+')
+				continue
+			}
+			if !strings.ContainsAny(elem, "{}") {
+				break
+			}
+			if elem[0] != '{' || elem[len(elem)-1] != '}' || strings.ContainsAny(elem[1:len(elem)-1], "{}") {
+				return fail(ErrInvalid, elem, "wildcards must span a whole path element")
+			}
+			var name = elem[1 : len(elem)-1]
+			switch {
+			case name == "$" && last:
+				// Trailing slash matched exactly.
+			case strings.HasSuffix(name, "...") && last && isIdentifier(name[:len(name)-3]):
+				sb.WriteByte('+')
+			case isIdentifier(name):
+				sb.WriteString(":" + name)
+			default:
+				return fail(ErrInvalid, elem, "invalid wildcard")
+			}
+			continue
+		}
+		if strings.ContainsAny(elem, "+?*") || elem != "" && elem[0] == ':' {
+			return fail(ErrUntranslatable, elem, "path element contains varouter tokens")
+		}
+		sb.WriteString(elem)
+	}
+	if _, err := varouter.Parse(sb.String(), varouter.DefaultTokens); err != nil {
+		return fail(ErrUntranslatable, pattern, err.Error())
+	}
+	return sb.String(), nil
+}
+
+// FromVarouter translates a varouter template to a pattern of the specified
+// dialect.
+func FromVarouter(template string, to Dialect) (string, error) {
+	var fail = func(err error, construct, reason string) (string, error) {
+		return "", &Error{template, construct, fmt.Errorf("%w: %s", err, reason)}
+	}
+	var t, err = varouter.Parse(template, varouter.DefaultTokens)
+	if err != nil {
+		return fail(ErrInvalid, template, err.Error())
+	}
+	if to == Varouter {
+		return template, nil
+	}
+	if t.Exclusion {
+		return fail(ErrUntranslatable, template[:1], "exclusions are not supported")
+	}
+	var segments = t.Segments
+	if t.Prefix {
+		segments = segments[:len(segments)-1]
+	}
+	var sb strings.Builder
+	for i, segment := range segments {
+		var last = i == len(segments)-1
+		switch segment.Kind {
+		case varouter.SegmentWildcard:
+			return fail(ErrUntranslatable, segment.Text, "wildcard patterns are not supported")
+		case varouter.SegmentVariable:
+			if to == ServeMux && !isIdentifier(segment.Name) {
+				return fail(ErrUntranslatable, segment.Text, "wildcard names must be Go identifiers")
+			}
+			if t.Prefix && segment.Name == restName {
+				return fail(ErrUntranslatable, segment.Text, "variable name is reserved for the prefix")
+			}
+			switch to {
+			case Gorilla, ServeMux:
+				sb.WriteString("/{" + segment.Name + "}")
+			case HTTPRouter:
+				sb.WriteString(segment.Text)
+			}
+			if last && t.Prefix {
+				// A prefix variable matches the rest of the path after
+				// its value; translate it as a variable followed by a
+				// rest wildcard.
+				writeRest(&sb, to)
+			}
+			continue
+		}
+		var reserved = "{}"
+		if to == HTTPRouter {
+			reserved = ":*"
+		}
+		if strings.ContainsAny(segment.Name, reserved) {
+			return fail(ErrUntranslatable, segment.Text, "path element contains "+to.String()+" tokens")
+		}
+		if last && t.Prefix {
+			if segment.Name != "" {
+				return fail(ErrUntranslatable, segment.Text+"+", "prefix path element names are not supported")
+			}
+			writeRest(&sb, to)
+			continue
+		}
+		sb.WriteString(segment.Text)
+		if last && to == ServeMux && segment.Name == "" {
+			sb.WriteString("{$}")
+		}
+	}
+	return sb.String(), nil
+}
+
+// writeRest writes a wildcard matching the rest of the path in the specified
+// dialect to sb.
+func writeRest(sb *strings.Builder, to Dialect) {
+	switch to {
+	case Gorilla:
+		sb.WriteString("/{" + restName + ":.*}")
+	case HTTPRouter:
+		sb.WriteString("/*" + restName)
+	case ServeMux:
+		sb.WriteString("/")
+	}
+}
+
+// closing returns the index of the brace closing the opening brace at the
+// start of s or -1 if not found.
+func closing(s string) int {
+	var depth int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isIdentifier returns if s is a Go identifier.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
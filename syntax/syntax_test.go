@@ -0,0 +1,109 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syntax
+
+import (
+	"errors"
+	"testing"
+)
+
+// Dialects are all dialects in order of goldenTranslations columns.
+var Dialects = []Dialect{Varouter, Gorilla, HTTPRouter, ServeMux}
+
+// goldenTranslations are equivalent patterns in all Dialects.
+var goldenTranslations = [][4]string{
+	{"/", "/", "/", "/{$}"},
+	{"/+", "/{rest:.*}", "/*rest", "/"},
+	{"/items", "/items", "/items", "/items"},
+	{"/items/:id", "/items/{id}", "/items/:id", "/items/{id}"},
+	{"/dir/", "/dir/", "/dir/", "/dir/{$}"},
+	{"/static/+", "/static/{rest:.*}", "/static/*rest", "/static/"},
+	{"/a/:b/c/:d/+", "/a/{b}/c/{d}/{rest:.*}", "/a/:b/c/:d/*rest", "/a/{b}/c/{d}/"},
+	{"/a.b:c/d", "/a.b:c/d", "-", "/a.b:c/d"},
+}
+
+func TestTranslateGolden(t *testing.T) {
+	for _, row := range goldenTranslations {
+		for i, from := range Dialects {
+			if row[i] == "-" {
+				continue
+			}
+			for j, to := range Dialects {
+				result, err := Translate(row[i], from, to)
+				if row[j] == "-" {
+					var e *Error
+					if !errors.As(err, &e) || !errors.Is(err, ErrUntranslatable) {
+						t.Fatalf("Translate(%q, %s, %s) = %q, '%v', expected untranslatable", row[i], from, to, result, err)
+					}
+					continue
+				}
+				if err != nil || result != row[j] {
+					t.Fatalf("Translate(%q, %s, %s) = %q, '%v', expected %q", row[i], from, to, result, err, row[j])
+				}
+			}
+		}
+	}
+}
+
+func TestTranslateLossy(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		from, to Dialect
+		expected string
+	}{
+		{"!/admin/+", Varouter, ServeMux, "/admin/"},
+		{"!/items/:id", Varouter, Gorilla, "/items/{id}"},
+		{"/src/*filepath", HTTPRouter, Varouter, "/src/+"},
+		{"/src/*filepath", HTTPRouter, Gorilla, "/src/{rest:.*}"},
+		{"/src/{path...}", ServeMux, Varouter, "/src/+"},
+		{"/src/{path:.*}", Gorilla, ServeMux, "/src/"},
+		{"/home/:user+", Varouter, ServeMux, "/home/{user}/"},
+		{"/home/:user+", Varouter, Gorilla, "/home/{user}/{rest:.*}"},
+		{"/home/:user+", Varouter, HTTPRouter, "/home/:user/*rest"},
+	} {
+		if result, err := Translate(test.pattern, test.from, test.to); err != nil || result != test.expected {
+			t.Fatalf("Translate(%q, %s, %s) = %q, '%v', expected %q", test.pattern, test.from, test.to, result, err, test.expected)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	for _, test := range []struct {
+		pattern   string
+		from, to  Dialect
+		construct string
+		err       error
+	}{
+		{"/items/{id:[0-9]+}", Gorilla, Varouter, "{id:[0-9]+}", ErrUntranslatable},
+		{"/file.{ext}", Gorilla, Varouter, "file.{ext}", ErrUntranslatable},
+		{"/a/{rest:.*}/b", Gorilla, Varouter, "{rest:.*}", ErrUntranslatable},
+		{"/{a}{b}", Gorilla, Varouter, "{a}{b}", ErrUntranslatable},
+		{"/{}", Gorilla, Varouter, "{}", ErrInvalid},
+		{"/a+b", Gorilla, Varouter, "a+b", ErrUntranslatable},
+		{"items", Gorilla, Varouter, "items", ErrInvalid},
+		{"/user_:name", HTTPRouter, Varouter, "user_:name", ErrUntranslatable},
+		{"/src/*path/x", HTTPRouter, Varouter, "*path", ErrInvalid},
+		{"/src/:", HTTPRouter, Varouter, ":", ErrInvalid},
+		{"GET /items", ServeMux, Varouter, "GET /items", ErrUntranslatable},
+		{"example.com/", ServeMux, Varouter, "example.com/", ErrUntranslatable},
+		{"/a/{x...}/b", ServeMux, Varouter, "{x...}", ErrInvalid},
+		{"/a{x}", ServeMux, Varouter, "a{x}", ErrInvalid},
+		{"/a/{$}/b", ServeMux, Varouter, "{$}", ErrInvalid},
+		{"^/admin", Varouter, Gorilla, "^", ErrUntranslatable},
+		{"/*.js", Varouter, ServeMux, "/*.js", ErrUntranslatable},
+		{"/ab+", Varouter, HTTPRouter, "/ab+", ErrUntranslatable},
+		{"/:user-id", Varouter, ServeMux, "/:user-id", ErrUntranslatable},
+		{"/a:b", Varouter, HTTPRouter, "/a:b", ErrUntranslatable},
+		{"/{a}", Varouter, Gorilla, "/{a}", ErrUntranslatable},
+		{"/:rest+", Varouter, Gorilla, "/:rest", ErrUntranslatable},
+		{"no", Varouter, Gorilla, "no", ErrInvalid},
+	} {
+		result, err := Translate(test.pattern, test.from, test.to)
+		var e *Error
+		if !errors.As(err, &e) || e.Construct != test.construct || !errors.Is(err, test.err) {
+			t.Fatalf("Translate(%q, %s, %s) = %q, '%v', expected '%v' for %q", test.pattern, test.from, test.to, result, err, test.err, test.construct)
+		}
+	}
+}
@@ -10,6 +10,7 @@ package varouter
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -20,6 +21,9 @@ var (
 	ErrRegister = fmt.Errorf("%w: register", ErrVarouter)
 	// ErrDuplicate is returned when a duplicate template is specified.
 	ErrDuplicate = fmt.Errorf("%w: duplicate template", ErrRegister)
+
+	// ErrSnapshot is returned when a snapshot cannot be loaded.
+	ErrSnapshot = fmt.Errorf("%w: snapshot", ErrVarouter)
 )
 
 // Vars is a map of variable names to their values parsed from a path.
@@ -30,8 +34,21 @@ type elements map[string]*element
 
 // element defines a path element.
 type element struct {
+	// name is the name of this element in its parent subs.
+	name string
 	// subs are the sub elements of this element.
+	// Subs of a compiled element may be nil and stored in literals instead.
 	subs elements
+	// literals, if not nil, are the sub elements of a compiled element
+	// sorted by name and are used instead of subs.
+	literals []*element
+	// wildcards are the wildcard sub elements sorted by name.
+	wildcards []*element
+	// prefixes, if not nil, is the root of a trie of prefix sub elements.
+	prefixes *prefixNode
+	// tail, if not empty, are names of literal elements that were merged
+	// into this element by radix compression which must follow its name.
+	tail string
 	// template, if not empty, specifies this element is the last element of
 	// a registered template and the value is the template.
 	template string
@@ -44,6 +61,12 @@ type element struct {
 	// isoverride specifies if this element is an override element.
 	// Value is ignored if this element template is empty.
 	isoverride bool
+	// isexclusion specifies if this element is an exclusion element.
+	// Value is ignored if this element template is empty.
+	isexclusion bool
+	// priority is the priority of the template ending at this element.
+	// Value is ignored if this element template is empty.
+	priority int
 	// iswildcard specifies if this element name has wildcards.
 	iswildcard bool
 	// hasprefixes specifies that one or more subs of this element have
@@ -57,6 +80,26 @@ type element struct {
 // newElement returns a new element instance.
 func newElement() *element { return &element{subs: make(elements)} }
 
+// sub returns the sub element by name and a truth if found.
+func (e *element) sub(name string) (elem *element, exists bool) {
+	if e.subs != nil {
+		elem, exists = e.subs[name]
+		return
+	}
+	var i, j, h = 0, len(e.literals), 0
+	for i < j {
+		if h = int(uint(i+j) >> 1); e.literals[h].name < name {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i < len(e.literals) && e.literals[i].name == name {
+		return e.literals[i], true
+	}
+	return nil, false
+}
+
 // Varouter is a flexible path matching router with support for path element
 // variables and wildcards for matching multiple templates that does not suffer
 // (greatly) on large number of registered items.
@@ -71,10 +114,18 @@ func newElement() *element { return &element{subs: make(elements)} }
 //
 // For details on use see Register and Match.
 type Varouter struct {
-	count int      // count is the number of registered templates.
-	root  *element // root is the root element.
+	count      int               // count is the number of registered templates.
+	overrides  int               // overrides is the number of override and exclusion templates.
+	root       *element          // root is the root element.
+	backend    Backend           // backend is the selected matching backend.
+	compressed bool              // compressed specifies if the tree is radix compressed.
+	cache      *matchCache       // cache, if not nil, caches Match results.
+	priorities map[string]int    // priorities maps templates to non-zero priorities.
+	routes     map[string]*Route // routes maps templates to registered routes.
+	names      map[string]string // names maps route names to templates.
 
 	override     byte // Override is the override character to use. Default: '!'.
+	exclusion    byte // Exclusion is the exclusion character to use. Default: '^'.
 	separator    byte // Separator is the path separator character to use. Default: '/'.
 	variable     byte // Variable is the variable placeholder character to use. Default: ':'.
 	prefix       byte // Prefix is the character that prefix character to use. Default: '+'.
@@ -82,25 +133,33 @@ type Varouter struct {
 	wildcardmany byte // WIldcardmany is the character that matches one or more characters. Default: '*';
 }
 
-// registerState maintains the template registration state.
-type registerState struct {
-	template *string  // template being registered.
-	current  *element // current element being matched against.
-	cursor   int      // cursor is the template scan position.
-	marker   int      // marker is the position from which an element name is extracted, up to cursor.
-	length   int      // length is the length of template.
-	override bool     // override denotes template is an override.
-	existing bool     // existing template was retrieved.
-}
-
 // matchState maintains the path matching state.
 type matchState struct {
 	current     *element  // current element being matched against.
-	path        *string   // path being matched.
+	path        string    // path being matched.
 	matches     *[]string // matches is a list templates matching path.
 	vars        *Vars     // vars hold the extracted variable values.
+	params      *Params   // params, if not nil, hold the extracted variable values instead of vars.
+	copyvars    bool      // copyvars specifies that variable values must be copied from path.
 	length      int       // length is the length of the path.
 	hasoverride bool      // hasoverride denotes an override match has been added to matches.
+	one         bool      // one specifies that matching stops at the first best match.
+	done        bool      // done denotes the best match was found if matching one.
+}
+
+// setVar sets the value of a parsed variable.
+func (state *matchState) setVar(name, value string) {
+	if state.params != nil {
+		state.params.set(name, value)
+		return
+	}
+	if state.vars == nil {
+		return
+	}
+	if state.copyvars {
+		value = strings.Clone(value)
+	}
+	(*state.vars)[name] = value
 }
 
 // New returns a new *Varouter instance with default configuration.
@@ -108,10 +167,13 @@ func New() *Varouter { return NewVarouter(false, '!', '/', ':', '+', '?', '*') }
 
 // NewVarouter returns a new *Varouter instance with the given override,
 // separator, variable, prefix, wildcard-one and wildcard-many characters.
+// The exclusion character defaults to '^' and can be changed using
+// SetExclusion.
 func NewVarouter(usewildcards bool, override, separator, variable, prefix, wildcardone, wildcardmany byte) *Varouter {
 	return &Varouter{
 		root:         newElement(),
 		override:     override,
+		exclusion:    '^',
 		separator:    separator,
 		variable:     variable,
 		prefix:       prefix,
@@ -120,9 +182,14 @@ func NewVarouter(usewildcards bool, override, separator, variable, prefix, wildc
 	}
 }
 
+// SetExclusion sets the exclusion character. It must be called before any
+// templates are registered.
+func (vr *Varouter) SetExclusion(exclusion byte) { vr.exclusion = exclusion }
+
 // Register registers a template which will be matched against a path specified
 // by Match method. If an error occurs during registration it is returned and
-// no template was registered.
+// no template was registered. Templates are parsed using Parse and an invalid
+// template is reported with a *ParseError.
 //
 // Template must be a rooted path, starting with the defined Separator.
 // Match path is matched exactly, including any possibly multiple Separators
@@ -150,151 +217,158 @@ func NewVarouter(usewildcards bool, override, separator, variable, prefix, wildc
 // overrides after a matched override template are not considered. Override
 // characters as part of template name are allowed.
 //
+// Templates can be defined as Exclusions by prefixing the template with the
+// exclusion character. An exclusion that matches a path removes the templates
+// it covers from the match result, following the same rules as overrides:
+// matches of less specific templates before it are discarded and matches of
+// templates that are not overrides after it are not considered. An override
+// more specific than a matched exclusion is still matched. If nothing remains
+// after exclusion Match reports no match. For example, "/api/+" and
+// "^/api/internal/+" match "/api/users" but not "/api/internal/users".
+//
 // Only one Placeholder per registered template tree path element level is
 // allowed. For example:
 // "/edit/:user" and "/export/:user" is allowed but
 // "/edit/:user" and "/edit/:admin" is not.
-func (vr *Varouter) Register(template string) (err error) {
-	var state registerState
-	state.template = &template
-	state.current = vr.root
-	if state.length = len(template); state.length < 1 {
-		return fmt.Errorf("%w: empty template name", ErrRegister)
-	}
-	for state.cursor = 0; state.cursor < state.length; state.cursor++ {
-		if template[state.cursor] == vr.prefix && state.cursor < state.length-1 {
-			return fmt.Errorf("%w: prefix character allowed only as suffix", ErrRegister)
-		}
-	}
-	state.cursor = 1
-	if (*state.template)[0] == vr.override {
-		state.override = true
-		state.marker++
-		state.cursor++
-	}
-	if (*state.template)[state.marker] != vr.separator {
-		return fmt.Errorf("%w: invalid template", ErrRegister)
+//
+// A template may be registered after templates it is a parent of. For
+// example, "/home" and "/home+" can be registered after "/home/users".
+// Registering a template that is already registered returns ErrDuplicate.
+//
+// Register registers the template with priority 0.
+// See RegisterPriority for details on priorities.
+func (vr *Varouter) Register(template string) error {
+	return vr.RegisterPriority(template, 0)
+}
+
+// RegisterPriority registers a template with the specified priority.
+//
+// Priority orders templates matched by Match in ascending order so that the
+// last matched template is the one with the highest priority. Templates of
+// equal priority retain their match order, from least to most specific.
+// Priority does not change which templates match a path; override and
+// exclusion rules are applied before matches are ordered.
+//
+// See Register for details on templates.
+func (vr *Varouter) RegisterPriority(template string, priority int) (err error) {
+	var t *Template
+	if t, err = Parse(template, vr.Tokens()); err != nil {
+		return err
 	}
-	for ; state.cursor < state.length; state.cursor++ {
-		if (*state.template)[state.cursor] != vr.separator {
-			continue
-		}
-		if err = vr.matchOrInsert(&state); err != nil {
-			return err
-		}
-		state.marker = state.cursor
+	if vr.compressed {
+		vr.expand(vr.root)
+		vr.compressed = false
 	}
-	if err = vr.matchOrInsert(&state); err != nil {
+	var elem *element
+	if elem, err = vr.insert(t); err != nil {
 		return err
 	}
-	if state.existing {
-		return fmt.Errorf("%w: '%s'", ErrDuplicate, template)
+	elem.template = template
+	elem.priority = priority
+	elem.isoverride = t.Override
+	elem.isexclusion = t.Exclusion
+	if t.Override || t.Exclusion {
+		vr.overrides++
 	}
-	// Mark the last element as override.
-	if state.override {
-		state.current.isoverride = true
+	vr.count++
+	if vr.cache != nil {
+		vr.cache.purge()
+	}
+	if priority != 0 {
+		if vr.priorities == nil {
+			vr.priorities = make(map[string]int)
+		}
+		vr.priorities[template] = priority
 	}
-	state.current.template = template
 	return nil
 }
 
-// matchOrInsert matches a single path element or inserts a new one if it does
-// not exist and updates element properties in the process.
-func (vr *Varouter) matchOrInsert(state *registerState) (err error) {
-	var name = (*state.template)[state.marker:state.cursor]
-	var namelen = len(name)
-	var prefix = name[namelen-1] == vr.prefix
-	if prefix {
-		name = name[:namelen-1]
-		namelen--
-	}
-	var elem *element
-	var exists bool
-	// Try exact match first.
-	if elem, exists = state.current.subs[name]; exists {
-		// If last element being matched and this registered element
-		// is not a template, error out.
-		if state.cursor == state.length && state.current.template != "" {
-			return fmt.Errorf("%w: '%s'", ErrDuplicate, elem.template)
+// insert returns the last element of a parsed template, inserting missing
+// elements into the tree. If an error occurs the tree is not modified.
+func (vr *Varouter) insert(t *Template) (elem *element, err error) {
+	var segments = t.Segments
+	if t.Prefix {
+		segments = segments[:len(segments)-1]
+	}
+	// Descend existing elements.
+	var parent, current, exists = vr.root, vr.root, false
+	var i int
+	for ; i < len(segments); i++ {
+		if elem, exists = current.subs[segments[i].Text]; !exists {
+			break
 		}
-		// Update state and advance to next registered level.
-		state.current = elem
-		state.existing = true
-		return nil
-	}
-	elem = newElement()
-	if prefix {
-		// Mark parent for match optimization.
-		state.current.hasprefixes = true
+		parent, current = current, elem
 	}
-	if elem.iswildcard = vr.hasWildcards(&name, &namelen); elem.iswildcard {
-		// Mark parent for match optimization.
-		state.current.haswildcards = true
-	}
-	// Register as variable.
-	if state.current.hasvariable != "" {
-		return fmt.Errorf("%w: element registration on a level with a variable", ErrRegister)
-	}
-	if namelen > 1 && name[1] == vr.variable {
-		if err = vr.validateVariableName(&name, &namelen); err != nil {
-			return
-		}
-		if len(state.current.subs) > 0 {
-			return fmt.Errorf("%w: multiple variable registrations on a path level", ErrRegister)
+	if i == len(segments) {
+		if elem.template != "" {
+			return nil, fmt.Errorf("%w: '%s'", ErrDuplicate, elem.template)
 		}
-		if elem.iswildcard {
-			return fmt.Errorf("%w: variable names cannot contain wildcards", ErrRegister)
+		if t.Prefix {
+			markPrefix(parent, elem)
 		}
-		state.current.hasvariable = name
-	}
-	// If this is the last element and its last char is a prefix
-	// character, set eventual preparsed flags.
-	if state.cursor >= state.length {
-		elem.isprefix = prefix
-		elem.isoverride = state.override
-	}
-	// Add item.
-	state.current.subs[name] = elem
-	state.current = elem
-	state.existing = false
-	vr.count++
-	return nil
-}
-
-// hasWildcards returns if specified name contains wildcard characters.
-func (vr *Varouter) hasWildcards(name *string, namelen *int) bool {
-	for i := 0; i < *namelen; i++ {
-		if (*name)[i] == vr.wildcardone || (*name)[i] == vr.wildcardmany {
-			return true
+		return elem, nil
+	}
+	// Validate the level at which new elements are inserted.
+	if current.hasvariable != "" {
+		return nil, fmt.Errorf("%w: element registration on a level with a variable", ErrRegister)
+	}
+	if segments[i].Kind == SegmentVariable && len(current.subs) > 0 {
+		return nil, fmt.Errorf("%w: multiple variable registrations on a path level", ErrRegister)
+	}
+	// Insert new elements.
+	for ; i < len(segments); i++ {
+		parent, elem = current, newElement()
+		elem.name = segments[i].Text
+		parent.subs[elem.name] = elem
+		switch segments[i].Kind {
+		case SegmentVariable:
+			parent.hasvariable = elem.name
+		case SegmentWildcard:
+			elem.iswildcard = true
+			parent.haswildcards = true
+			parent.wildcards = insertWildcard(parent.wildcards, elem)
 		}
+		current = elem
 	}
-	return false
+	if t.Prefix {
+		markPrefix(parent, elem)
+	}
+	return elem, nil
 }
 
-// isValidVariableName returns an error if variable name is invalid.
-func (vr *Varouter) validateVariableName(name *string, namelen *int) error {
-	if *namelen <= 2 {
-		return fmt.Errorf("%w: empty variable name", ErrRegister)
-	}
-	for i := 2; i < *namelen; i++ {
-		if (*name)[i] == vr.variable {
-			return fmt.Errorf("%w: invalid variable name", ErrRegister)
-		}
-	}
-	return nil
+// markPrefix marks elem, a sub of parent, as a prefix element.
+func markPrefix(parent, elem *element) {
+	elem.isprefix = true
+	parent.hasprefixes = true
+	if parent.prefixes == nil {
+		parent.prefixes = &prefixNode{}
+	}
+	parent.prefixes.insert(elem)
 }
 
 // Match matches a path against registered templates and returns the names of
 // matched templates, a map of parsed param names to param values and a bool
 // indicating if a match occured and previous two result vars are valid.
 //
-// See Register for details on how the path is matched against templates.
+// See Register for details on how the path is matched against templates and
+// RegisterPriority for details on the order of matched templates.
 //
 // If no templates were matched the resulting templates will be nil.
 // If no params were parsed from the path the resulting ParamMap wil be nil.
+//
+// If a cache was set using SetCache results are served from and stored to it.
 func (vr *Varouter) Match(path string) (matches []string, vars Vars, matched bool) {
+	if vr.cache != nil {
+		var ok bool
+		if matches, vars, matched, ok = vr.cache.get(path); ok {
+			return
+		}
+	}
 	vars = make(Vars)
-	matched = vr.match(&path, &matches, &vars)
+	matched = vr.match(&path, &matches, &vars, nil, false)
+	if vr.cache != nil {
+		vr.cache.put(path, matches, vars, matched)
+	}
 	return
 }
 
@@ -305,30 +379,122 @@ func (vr *Varouter) Match(path string) (matches []string, vars Vars, matched boo
 // Vars is a pointer to a map into which parsed variables will be stored into.
 // Returns a boolean denoting if anything was matched.
 func (vr *Varouter) MatchTo(path *string, matches *[]string, vars *Vars) bool {
-	return vr.match(path, matches, vars)
+	return vr.match(path, matches, vars, nil, false)
+}
+
+// MatchOne matches a path against registered templates and returns only the
+// template MatchTop would return, a map of parsed param names to param values
+// and a bool indicating if a match occured and previous two result vars are
+// valid.
+//
+// Unlike MatchTop, MatchOne stops searching as soon as the best possible
+// match is found instead of collecting all matching templates. If any
+// override, exclusion or prioritized templates are registered the search
+// cannot be pruned and MatchOne performs as MatchTop.
+func (vr *Varouter) MatchOne(path string) (template string, vars Vars, matched bool) {
+	if vr.overrides > 0 || len(vr.priorities) > 0 {
+		return vr.MatchTop(path)
+	}
+	// Variables are parsed from the matched template as the search may bind
+	// variables in branches it abandons.
+	var matches = make([]string, 0, 1)
+	if matched = vr.match(&path, &matches, nil, nil, true); matched {
+		template = matches[0]
+		vars = vr.templateVars(template, path)
+	}
+	return
 }
 
-// match is the implementation of Match and MatchTo.
-func (vr *Varouter) match(path *string, matches *[]string, vars *Vars) bool {
+// match is the implementation of Match, MatchTo, MatchOne and MatchWith.
+// Parsed variables are stored to params if not nil, otherwise to vars.
+func (vr *Varouter) match(path *string, matches *[]string, vars *Vars, params *Params, one bool) bool {
 	var state = matchState{
 		current: vr.root,
-		path:    path,
+		path:    *path,
 		length:  len(*path),
 		matches: matches,
 		vars:    vars,
+		params:  params,
+		one:     one,
 	}
+	return vr.matchPath(&state)
+}
+
+// matchPath matches state.path and orders resulting matches.
+func (vr *Varouter) matchPath(state *matchState) bool {
 	if state.length < 1 {
 		return false
 	}
-	vr.nextLevel(0, &state)
-	return len(*matches) > 0
+	vr.nextLevel(0, state)
+	if len(vr.priorities) > 0 {
+		vr.sortMatches(*state.matches)
+	}
+	return len(*state.matches) > 0
+}
+
+// sortMatches stable sorts matches by ascending template priority in place.
+func (vr *Varouter) sortMatches(matches []string) {
+	var i, j int
+	var t string
+	var p int
+	for i = 1; i < len(matches); i++ {
+		t = matches[i]
+		p = vr.priorities[t]
+		for j = i; j > 0 && vr.priorities[matches[j-1]] > p; j-- {
+			matches[j] = matches[j-1]
+		}
+		matches[j] = t
+	}
+}
+
+// MatchTop matches a path against registered templates and returns the
+// matched template with the highest priority, a map of parsed param names to
+// param values and a bool indicating if a match occured and previous two
+// result vars are valid.
+//
+// If more than one template of the highest priority matched, the most
+// specific one is returned. Only variables of the returned template are
+// returned.
+func (vr *Varouter) MatchTop(path string) (template string, vars Vars, matched bool) {
+	var matches []string
+	if matches, _, matched = vr.Match(path); matched {
+		template = matches[len(matches)-1]
+		vars = vr.templateVars(template, path)
+	}
+	return
+}
+
+// templateVars returns values of variables of a template matching path
+// parsed from path or nil if template has no variables.
+func (vr *Varouter) templateVars(template, path string) (vars Vars) {
+	if template[0] == vr.override || template[0] == vr.exclusion {
+		template = template[1:]
+	}
+	if template[len(template)-1] == vr.prefix {
+		template = template[:len(template)-1]
+	}
+	var tmarker, tcursor, pmarker, pcursor int
+	for tmarker < len(template) && pmarker < len(path) {
+		for tcursor = tmarker + 1; tcursor < len(template) && template[tcursor] != vr.separator; tcursor++ {
+		}
+		for pcursor = pmarker + 1; pcursor < len(path) && path[pcursor] != vr.separator; pcursor++ {
+		}
+		if tcursor-tmarker > 2 && template[tmarker+1] == vr.variable {
+			if vars == nil {
+				vars = make(Vars)
+			}
+			vars[template[tmarker+2:tcursor]] = path[pmarker+1 : pcursor]
+		}
+		tmarker, pmarker = tcursor, pcursor
+	}
+	return
 }
 
 // nextLevel advances matching to the next path level.
 func (vr *Varouter) nextLevel(marker int, state *matchState) {
 	var cursor int
 	for cursor = marker + 1; cursor < state.length; cursor++ {
-		if (*state.path)[cursor] != vr.separator {
+		if state.path[cursor] != vr.separator {
 			continue
 		}
 		if vr.matchLevel(cursor, marker, state) {
@@ -349,20 +515,26 @@ func (vr *Varouter) matchLevel(cursor, marker int, state *matchState) (stop bool
 	// Extract current level name.
 	var name string
 	if cursor >= state.length {
-		name = (*state.path)[marker:]
+		name = state.path[marker:]
 	} else {
-		name = (*state.path)[marker:cursor]
+		name = state.path[marker:cursor]
 	}
 	var namelen = len(name)
 	if name == "" {
 		return
 	}
+	if state.one {
+		return vr.matchLevelOne(cursor, marker, &name, state)
+	}
 	// If element is a variable holder, retrieve the sub element by
 	// variable name, add the current level name as variable value
 	// and advance to next level.
 	if state.current.hasvariable != "" {
-		(*state.vars)[state.current.hasvariable[2:]] = (*state.path)[marker+1 : cursor]
-		state.current = state.current.subs[state.current.hasvariable]
+		state.setVar(state.current.hasvariable[2:], state.path[marker+1:cursor])
+		state.current, _ = state.current.sub(state.current.hasvariable)
+		if !vr.matchTail(&cursor, state) {
+			return true
+		}
 		if state.current.isprefix {
 			stop = vr.addMatch(&cursor, state)
 		} else {
@@ -371,38 +543,40 @@ func (vr *Varouter) matchLevel(cursor, marker int, state *matchState) (stop bool
 		if stop {
 			return
 		}
-		cursor += len(name)
 		vr.nextLevel(cursor, state)
-		return
+		return true
 	}
-	// Iterate subs if required.
-	var subname string
-	var subnamelen int
+	// Match against any wildcards and prefixes.
 	var subelem *element
 	var saveelem = state.current
-	if state.current.haswildcards || state.current.hasprefixes {
-		for subname, subelem = range state.current.subs {
-			subnamelen = len(subname)
-			// Match against any wildcards.
-			if subelem.iswildcard && vr.matchWildcard(&name, &subname) {
-				state.current = subelem
-				vr.maybeAddMatch(&cursor, state)
-				vr.nextLevel(cursor, state)
-				state.current = saveelem
-			}
-			// Match agains any prefixes.
-			if subelem.isprefix && namelen >= subnamelen && name[0:subnamelen] == subname {
-				state.current = subelem
-				vr.addMatch(&cursor, state)
-				vr.nextLevel(cursor, state)
-				state.current = saveelem
-			}
+	for _, subelem = range saveelem.wildcards {
+		if vr.matchWildcard(&name, &subelem.name) {
+			state.current = subelem
+			vr.maybeAddMatch(&cursor, state)
+			vr.nextLevel(cursor, state)
+			state.current = saveelem
 		}
 	}
-	// Finally, try an exact match.
+	for node, i := saveelem.prefixes, 0; node != nil; i++ {
+		if node.elem != nil {
+			state.current = node.elem
+			vr.addMatch(&cursor, state)
+			vr.nextLevel(cursor, state)
+			state.current = saveelem
+		}
+		if i >= namelen {
+			break
+		}
+		node = node.child(name[i])
+	}
+	// Finally, try an exact match. Prefix and wildcard subs were already
+	// matched above, don't visit them twice.
 	var exists bool
-	if subelem, exists = state.current.subs[name]; exists {
+	if subelem, exists = state.current.sub(name); exists && !subelem.isprefix && !subelem.iswildcard {
 		state.current = subelem
+		if !vr.matchTail(&cursor, state) {
+			return true
+		}
 		if vr.maybeAddMatch(&cursor, state) {
 			return true
 		}
@@ -412,6 +586,90 @@ func (vr *Varouter) matchLevel(cursor, marker int, state *matchState) (stop bool
 	return true
 }
 
+// matchLevelOne is the matchLevel implementation when matching one template.
+//
+// It visits registered template levels in the reverse of the order in which
+// matchLevel visits them and adds the current level to matches only after
+// deeper levels were matched so the first added match is the one matchLevel
+// would have added last. Matching stops as soon as a match is added.
+//
+// Deeper levels leave state.current at the element they matched last so it
+// is restored after each advance.
+func (vr *Varouter) matchLevelOne(cursor, marker int, name *string, state *matchState) (stop bool) {
+	// Variable holders have a single sub element.
+	if state.current.hasvariable != "" {
+		state.setVar(state.current.hasvariable[2:], state.path[marker+1:cursor])
+		var varelem, _ = state.current.sub(state.current.hasvariable)
+		state.current = varelem
+		if !vr.matchTail(&cursor, state) {
+			return true
+		}
+		vr.nextLevel(cursor, state)
+		state.current = varelem
+		vr.addOne(&cursor, state)
+		return true
+	}
+	// Try an exact match first as matchLevel tries it last.
+	var saveelem = state.current
+	var subelem, exists = saveelem.sub(*name)
+	if exists && !subelem.isprefix && !subelem.iswildcard {
+		var next = cursor
+		state.current = subelem
+		if vr.matchTail(&next, state) {
+			vr.nextLevel(next, state)
+			state.current = subelem
+			if vr.addOne(&next, state) {
+				return true
+			}
+		}
+		state.current = saveelem
+	}
+	if saveelem.prefixes != nil {
+		for node := saveelem.prefixes.last(name); node != nil; node = node.up {
+			if node.elem != nil && vr.descendOne(cursor, node.elem, saveelem, state) {
+				return true
+			}
+		}
+	}
+	for i := len(saveelem.wildcards) - 1; i >= 0; i-- {
+		subelem = saveelem.wildcards[i]
+		if vr.matchWildcard(name, &subelem.name) && vr.descendOne(cursor, subelem, saveelem, state) {
+			return true
+		}
+	}
+	return true
+}
+
+// descendOne advances matching one template to the next level of subelem
+// and adds subelem if nothing was matched in deeper levels. If matching is
+// not done state.current is restored to saveelem. Result denotes if matching
+// is done.
+func (vr *Varouter) descendOne(cursor int, subelem, saveelem *element, state *matchState) bool {
+	state.current = subelem
+	vr.nextLevel(cursor, state)
+	state.current = subelem
+	if vr.addOne(&cursor, state) {
+		return true
+	}
+	state.current = saveelem
+	return false
+}
+
+// addOne adds the current level to state.matches if matching one template,
+// no match was added yet and the current level matches. Result denotes if
+// matching is done.
+func (vr *Varouter) addOne(cursor *int, state *matchState) (done bool) {
+	if !state.done {
+		if state.current.isprefix {
+			vr.addMatch(cursor, state)
+		} else {
+			vr.maybeAddMatch(cursor, state)
+		}
+		state.done = len(*state.matches) > 0
+	}
+	return state.done
+}
+
 // maybeAddMatch maybe adds the current level to state.matches if:
 // This level is not a prefix template.
 // State.current item is the last element of a registered template.
@@ -434,6 +692,13 @@ func (vr *Varouter) maybeAddMatch(cursor *int, state *matchState) (added bool) {
 
 // addMatch adds state.current.template to a list of matches.
 func (vr *Varouter) addMatch(cursor *int, state *matchState) (added bool) {
+	// If current match is an exclusion, clear other matches and
+	// treat it as an override that matches nothing.
+	if state.current.isexclusion {
+		state.hasoverride = true
+		*state.matches = (*state.matches)[:0]
+		return false
+	}
 	// If current match is an override, clear other matches.
 	if state.current.isoverride {
 		state.hasoverride = true
@@ -517,5 +782,6 @@ func (vr *Varouter) DefinedTemplates() (a []string) {
 	return a
 }
 
-// NumTemplates returns number of defined templates.
+// NumTemplates returns number of registered templates. Path elements that
+// are only parents of registered templates are not counted.
 func (vr *Varouter) NumTemplates() int { return vr.count }
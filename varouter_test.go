@@ -6,6 +6,7 @@ package varouter
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 
@@ -40,6 +41,7 @@ func debugPrintElement(e *element, indent int) {
 	ind := strings.Repeat("\t", indent)
 	fmt.Printf("%sNum subs:           '%d'\n", ind, len(e.subs))
 	fmt.Printf("%sTemplate:           '%s'\n", ind, e.template)
+	fmt.Printf("%sTail:               '%s'\n", ind, e.tail)
 	fmt.Printf("%sIs Override:        '%t'\n", ind, e.isoverride)
 	fmt.Printf("%sIs Prefix:          '%t'\n", ind, e.isprefix)
 	fmt.Printf("%sIs Wildcard:        '%t'\n", ind, e.iswildcard)
@@ -104,6 +106,13 @@ var RegistrationTests = []RegistrationData{
 	{"/a/b/:c/:d", false, ""},
 	{"!/b", false, ""},
 	{"!/b/c", false, ""},
+	{"^/e", false, ""},
+	{"^/e/+", false, ""},
+	{"^/e/:f", true, "Failed detecting variable being registered on a path level with registered elements."},
+	{"/x/y/z", false, ""},
+	{"/x/y", false, ""},
+	{"/x+", false, ""},
+	{"/x/y+", true, "Failed detecting existing template."},
 }
 
 func TestRegister(t *testing.T) {
@@ -119,6 +128,24 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestNumTemplates(t *testing.T) {
+	vr := New()
+	for _, template := range []string{"/home/users/vedran", "/home", "/home/users+"} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vr.Register("/home") == nil {
+		t.Fatal("Failed detecting existing template.")
+	}
+	if n := vr.NumTemplates(); n != 3 {
+		t.Fatalf("NumTemplates failed: %d", n)
+	}
+	if n := len(vr.DefinedTemplates()); n != 3 {
+		t.Fatalf("DefinedTemplates failed: %d", n)
+	}
+}
+
 // FailMatchTest fails a Match test and prints error details.
 func FailMatchTest(t *testing.T, match Match, result []string, ph Vars, expected bool) {
 	t.Fatalf(`
@@ -131,21 +158,46 @@ func FailMatchTest(t *testing.T, match Match, result []string, ph Vars, expected
 	  Matched:      '%#+v'`, match, result, ph, expected)
 }
 
-// RunMatchTests runs match tests.
+// Backends are the backends match tests are run against.
+var Backends = []Backend{BackendMap, BackendRadix}
+
+// RunMatchTests runs match tests against all Backends.
 func RunMatchTests(t *testing.T, tests []MatchTest) {
+	for _, backend := range Backends {
+		runMatchTests(t, tests, backend)
+	}
+}
+
+// runMatchTests runs match tests against the specified backend.
+func runMatchTests(t *testing.T, tests []MatchTest, backend Backend) {
 	for _, matchtest := range tests {
 		vr := New()
+		vr.SetBackend(backend)
 		for _, pattern := range matchtest.RegisteredPatterns {
 			if err := vr.Register(pattern); err != nil {
 				t.Fatal(err)
 			}
 		}
+		vr.Freeze()
 		for _, match := range matchtest.Matches {
 			patterns, variables, matched := vr.Match(match.Path)
 			if matched != match.ExpectedMatch {
 				DebugPrintElements(vr)
 				FailMatchTest(t, match, patterns, variables, matched)
 			}
+			if template, onevars, one := vr.MatchOne(match.Path); one != matched ||
+				(one && template != patterns[len(patterns)-1]) {
+				DebugPrintElements(vr)
+				t.Fatalf("MatchOne failed: '%s', expected '%v'", template, patterns)
+			} else if toptemplate, topvars, _ := vr.MatchTop(match.Path); template != toptemplate ||
+				fmt.Sprint(onevars) != fmt.Sprint(topvars) {
+				t.Fatalf("MatchOne returned '%s', '%v', MatchTop returned '%s', '%v'", template, onevars, toptemplate, topvars)
+			}
+			if cpatterns, cvariables, cmatched := vr.Compile().Match(match.Path); cmatched != matched ||
+				fmt.Sprint(sortedStrings(cpatterns), cvariables) != fmt.Sprint(sortedStrings(patterns), variables) {
+				DebugPrintElements(vr)
+				FailMatchTest(t, match, cpatterns, cvariables, cmatched)
+			}
 			for _, expectedpattern := range match.ExpectedPatterns {
 				found := false
 				for i := 0; i < len(patterns); i++ {
@@ -172,6 +224,13 @@ func RunMatchTests(t *testing.T, tests []MatchTest) {
 	}
 }
 
+// sortedStrings returns a sorted copy of a.
+func sortedStrings(a []string) []string {
+	var b = append([]string(nil), a...)
+	sort.Strings(b)
+	return b
+}
+
 // Match is a definition of expected match results.
 type Match struct {
 	Path              string   // Path to test against.
@@ -303,6 +362,43 @@ var MatchVariableTests = []MatchTest{
 			},
 		},
 	},
+	{
+		RegisteredPatterns: []string{
+			"/:user/:application",
+		},
+		Matches: []Match{
+			{
+				Path:              "/vedran/myapp",
+				ExpectedPatterns:  []string{"/:user/:application"},
+				Expectedvariables: Vars{"user": "vedran", "application": "myapp"},
+				ExpectedMatch:     true,
+			},
+			{
+				// Levels following a variable level must be matched from
+				// the end of the variable value.
+				Path:              "/u/a/x",
+				ExpectedPatterns:  nil,
+				Expectedvariables: nil,
+				ExpectedMatch:     false,
+			},
+		},
+	},
+	{
+		RegisteredPatterns: []string{
+			"/+",
+			"/a/:x/b",
+		},
+		Matches: []Match{
+			{
+				// Variables of a branch that did not match are not
+				// returned by MatchOne and MatchTop.
+				Path:              "/a/foo/c",
+				ExpectedPatterns:  []string{"/+"},
+				Expectedvariables: nil,
+				ExpectedMatch:     true,
+			},
+		},
+	},
 }
 
 func TestVariableMatch(t *testing.T) {
@@ -389,6 +485,47 @@ func TestOverrideMatch(t *testing.T) {
 	RunMatchTests(t, MatchOverrideTests)
 }
 
+var MatchExclusionTests = []MatchTest{
+	{
+		RegisteredPatterns: []string{
+			"/api/+",
+			"^/api/internal/+",
+			"!/api/internal/health",
+			"^/api/secret",
+		},
+		Matches: []Match{
+			{
+				Path:              "/api/users",
+				ExpectedPatterns:  []string{"/api/+"},
+				Expectedvariables: nil,
+				ExpectedMatch:     true,
+			},
+			{
+				Path:              "/api/internal/users",
+				ExpectedPatterns:  nil,
+				Expectedvariables: nil,
+				ExpectedMatch:     false,
+			},
+			{
+				Path:              "/api/internal/health",
+				ExpectedPatterns:  []string{"!/api/internal/health"},
+				Expectedvariables: nil,
+				ExpectedMatch:     true,
+			},
+			{
+				Path:              "/api/secret",
+				ExpectedPatterns:  nil,
+				Expectedvariables: nil,
+				ExpectedMatch:     false,
+			},
+		},
+	},
+}
+
+func TestExclusionMatch(t *testing.T) {
+	RunMatchTests(t, MatchExclusionTests)
+}
+
 var MatchWildcardTests = []MatchTest{
 	{
 		RegisteredPatterns: []string{
@@ -511,6 +648,58 @@ func TestCombined4(t *testing.T) {
 	RunMatchTests(t, MatchCombinedTests4)
 }
 
+func TestMatchPrefixAndWildcardOnce(t *testing.T) {
+	vr := New()
+	for _, template := range []string{"/home+", "/home/:user", "/*.d/conf"} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct{ path, expected string }{
+		{"/home/vedran", "[/home+ /home/:user]"},
+		{"/*.d/conf", "[/*.d/conf]"},
+	} {
+		if matches, _, _ := vr.Match(test.path); fmt.Sprint(matches) != test.expected {
+			t.Fatalf("Match('%s') returned '%v', expected '%s'", test.path, matches, test.expected)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	vr := New()
+	for _, test := range []struct {
+		Template string
+		Priority int
+	}{
+		{"/+", 2},
+		{"/home+", 1},
+		{"/home/:user", 0},
+	} {
+		if err := vr.RegisterPriority(test.Template, test.Priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matches, _, matched := vr.Match("/home/vedran")
+	if !matched || fmt.Sprint(matches) != "[/home/:user /home+ /+]" {
+		t.Fatalf("Priority order failed: '%v'", matches)
+	}
+	template, vars, matched := vr.MatchTop("/home/vedran")
+	if !matched || template != "/+" || vars != nil {
+		t.Fatalf("MatchTop failed: '%s', '%v', '%t'", template, vars, matched)
+	}
+	if err := vr.RegisterPriority("/home/:user/.config/:app", 3); err != nil {
+		t.Fatal(err)
+	}
+	template, vars, matched = vr.MatchTop("/home/vedran/.config/myapp")
+	if !matched || template != "/home/:user/.config/:app" || len(vars) != 2 ||
+		vars["user"] != "vedran" || vars["app"] != "myapp" {
+		t.Fatalf("MatchTop failed: '%s', '%v', '%t'", template, vars, matched)
+	}
+	if _, _, matched = vr.MatchTop(""); matched {
+		t.Fatal("MatchTop matched an empty path.")
+	}
+}
+
 func TestWildcardMatcher(t *testing.T) {
 	vr := NewVarouter(false, '!', '/', ':', '+', '?', '*')
 	text := "sinferopopokatepetl"
@@ -580,18 +769,32 @@ func makeBenchData(iterations int, maxPathElems, maxPathLength int) *BenchData {
 	return data
 }
 
-func BenchmarkMatch_8ElemNumX8Namelen(b *testing.B) {
+// benchmarkMatch benchmarks match with paths of a Varouter using backend
+// with templates of elems elements with names of namelen characters.
+func benchmarkMatch(b *testing.B, backend Backend, elems, namelen int, match func(vr *Varouter, path string)) {
 	vr := New()
-	data := makeBenchData(b.N, 8, 8)
+	vr.SetBackend(backend)
+	data := makeBenchData(b.N, elems, namelen)
 	for _, template := range data.Templates {
 		vr.Register(template)
 	}
+	if backend == BackendRadix {
+		vr.Freeze()
+	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		vr.Match(data.Paths[i])
+		match(vr, data.Paths[i])
 	}
 }
 
+// matchAll and matchOne are match functions of benchmarks.
+func matchAll(vr *Varouter, path string) { vr.Match(path) }
+func matchOne(vr *Varouter, path string) { vr.MatchOne(path) }
+
+func BenchmarkMatch_8ElemNumX8Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 8, 8, matchAll)
+}
+
 func BenchmarkMatch_8ElemNumX8NamelenPrealloc(b *testing.B) {
 	vr := New()
 	vars := make(Vars)
@@ -607,15 +810,7 @@ func BenchmarkMatch_8ElemNumX8NamelenPrealloc(b *testing.B) {
 }
 
 func BenchmarkMatch_64ElemNumX64Namelen(b *testing.B) {
-	vr := New()
-	data := makeBenchData(b.N, 64, 64)
-	for _, template := range data.Templates {
-		vr.Register(template)
-	}
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		vr.Match(data.Paths[i])
-	}
+	benchmarkMatch(b, BackendMap, 64, 64, matchAll)
 }
 
 func BenchmarkMatch_64ElemNumX64NamelenPrealloc(b *testing.B) {
@@ -633,29 +828,71 @@ func BenchmarkMatch_64ElemNumX64NamelenPrealloc(b *testing.B) {
 }
 
 func BenchmarkMatch_8ElemNumX64Namelen(b *testing.B) {
-	vr := New()
-	data := makeBenchData(b.N, 8, 64)
-	for _, template := range data.Templates {
-		vr.Register(template)
-	}
+	benchmarkMatch(b, BackendMap, 8, 64, matchAll)
+}
+
+func BenchmarkMatch_64ElemNumX8Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 64, 8, matchAll)
+}
+
+func BenchmarkMatchRadix_8ElemNumX8Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendRadix, 8, 8, matchAll)
+}
+
+func BenchmarkMatchRadix(b *testing.B) {
+	vl := New()
+	vl.SetBackend(BackendRadix)
+	vl.Register("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
+	vl.Freeze()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		vr.Match(data.Paths[i])
+		vl.Match("/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
 	}
 }
 
-func BenchmarkMatch_64ElemNumX8Namelen(b *testing.B) {
-	vr := New()
-	data := makeBenchData(b.N, 64, 8)
-	for _, template := range data.Templates {
-		vr.Register(template)
+func BenchmarkMatchOne_8ElemNumX8Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 8, 8, matchOne)
+}
+
+func BenchmarkMatchOne_64ElemNumX64Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 64, 64, matchOne)
+}
+
+func BenchmarkMatchOne_8ElemNumX64Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 8, 64, matchOne)
+}
+
+func BenchmarkMatchOne_64ElemNumX8Namelen(b *testing.B) {
+	benchmarkMatch(b, BackendMap, 64, 8, matchOne)
+}
+
+// registerPrefixBench registers a prefix and a wildcard template at each
+// level of path and the path itself as an exact template.
+func registerPrefixBench(vr *Varouter, path string) {
+	for i := 1; i < len(path); i++ {
+		if path[i] == '/' {
+			vr.Register(path[:i+1] + "+")
+			vr.Register(path[:i+1] + "*/x")
+		}
 	}
+	vr.Register(path)
+}
+
+// benchmarkPrefixes benchmarks match with a path whose every level matches
+// prefix and wildcard templates.
+func benchmarkPrefixes(b *testing.B, match func(vr *Varouter, path string)) {
+	vr := New()
+	registerPrefixBench(vr, "/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		vr.Match(data.Paths[i])
+		match(vr, "/home/users/vedran/Go/src/github.com/vedranvuk/varouter")
 	}
 }
 
+func BenchmarkMatch_Prefixes(b *testing.B) { benchmarkPrefixes(b, matchAll) }
+
+func BenchmarkMatchOne_Prefixes(b *testing.B) { benchmarkPrefixes(b, matchOne) }
+
 func ExampleVarouter() {
 	vr := New()
 	vr.Register("/+")
@@ -665,3 +902,31 @@ func ExampleVarouter() {
 	fmt.Printf("Templates: '%v', Params: '%v', Matched: '%t'\n", templates, params, matched)
 	// Output: Templates: '[/+ /dir/:var/+]', Params: 'map[var:val]', Matched: 'true'
 }
+
+// registerWideBench registers num literal templates, one wildcard and one
+// prefix template on the same level.
+func registerWideBench(vr *Varouter, num int) {
+	for i := 0; i < num; i++ {
+		vr.Register(fmt.Sprintf("/item%d/view", i))
+	}
+	vr.Register("/item*/edit")
+	vr.Register("/items+")
+}
+
+func BenchmarkMatch_WideLevel(b *testing.B) {
+	vr := New()
+	registerWideBench(vr, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vr.Match("/item5000/view")
+	}
+}
+
+func BenchmarkMatchOne_WideLevel(b *testing.B) {
+	vr := New()
+	registerWideBench(vr, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vr.MatchOne("/item5000/view")
+	}
+}
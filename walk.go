@@ -0,0 +1,141 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import "errors"
+
+// SkipSubtree can be returned by a Walk function to skip sub nodes of the
+// visited node. It is not returned as an error by Walk.
+var SkipSubtree = errors.New("skip subtree")
+
+// NodeKind is the kind of a registered template tree node.
+type NodeKind int
+
+const (
+	// NodeLiteral is a node whose name is matched exactly.
+	NodeLiteral NodeKind = iota
+	// NodeVariable is a node whose name is a variable.
+	NodeVariable
+	// NodeWildcard is a node whose name contains wildcards.
+	NodeWildcard
+	// NodePrefix is the last node of a prefix template. Its name may also
+	// contain wildcards.
+	NodePrefix
+)
+
+// String implements fmt.Stringer.
+func (k NodeKind) String() string {
+	switch k {
+	case NodeLiteral:
+		return "literal"
+	case NodeVariable:
+		return "variable"
+	case NodeWildcard:
+		return "wildcard"
+	case NodePrefix:
+		return "prefix"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k NodeKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// NodeInfo describes a node of the registered template tree visited by Walk.
+type NodeInfo struct {
+	// Depth is the template level of the node, 0 for first level nodes.
+	Depth int
+	// Name is the node name including the leading separator and excluding
+	// the prefix character, i.e. "/home", "/:user" or "/*.go".
+	Name string
+	// Path is the concatenation of names of nodes from the first level down
+	// to and including the node.
+	Path string
+	// Kind is the node kind.
+	Kind NodeKind
+	// Terminal specifies if a registered template ends at the node.
+	Terminal bool
+	// Template is the registered template ending at the node, if Terminal.
+	Template string
+	// Override specifies if Template is an override template.
+	Override bool
+	// Exclusion specifies if Template is an exclusion template.
+	Exclusion bool
+	// Priority is the priority of Template.
+	Priority int
+}
+
+// Walk walks the registered template tree depth first, calling fn for each
+// node before its sub nodes. Sub nodes are visited in order of their names.
+//
+// If fn returns SkipSubtree sub nodes of the visited node are skipped. If fn
+// returns any other error Walk stops and returns it.
+//
+// Nodes are reported as registered regardless of the selected backend.
+//
+// Walk is not safe for concurrent use with Register or Freeze.
+func (vr *Varouter) Walk(fn func(node NodeInfo) error) error {
+	return vr.walk(vr.root, "", 0, fn)
+}
+
+// walk calls fn for sub elements of e and their sub elements recursively.
+// Path is the path of e and depth the depth of its sub elements.
+func (vr *Varouter) walk(e *element, path string, depth int, fn func(node NodeInfo) error) (err error) {
+subs:
+	for _, sub := range sortedSubs(e) {
+		var node = NodeInfo{
+			Depth: depth,
+			Name:  sub.name,
+			Path:  path + sub.name,
+		}
+		switch {
+		case sub.isprefix:
+			node.Kind = NodePrefix
+		case sub.iswildcard:
+			node.Kind = NodeWildcard
+		case sub.name == e.hasvariable:
+			node.Kind = NodeVariable
+		}
+		// Report elements merged into sub by radix compression as
+		// literal nodes, the last of which holds sub properties.
+		var tail, cursor = sub.tail, 0
+		for tail != "" {
+			if err = fn(node); err == SkipSubtree {
+				continue subs
+			}
+			if err != nil {
+				return
+			}
+			for cursor = 1; cursor < len(tail) && tail[cursor] != vr.separator; cursor++ {
+			}
+			node.Depth++
+			node.Name = tail[:cursor]
+			node.Path += node.Name
+			node.Kind = NodeLiteral
+			tail = tail[cursor:]
+		}
+		if sub.template != "" {
+			node.Terminal = true
+			node.Template = sub.template
+			node.Override = sub.isoverride
+			node.Exclusion = sub.isexclusion
+			node.Priority = sub.priority
+		}
+		if err = fn(node); err == SkipSubtree {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if err = vr.walk(sub, node.Path, node.Depth+1, fn); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// Walk walks the compiled template tree.
+// See Varouter.Walk for details.
+func (m *Matcher) Walk(fn func(node NodeInfo) error) error { return m.vr.Walk(fn) }
@@ -0,0 +1,95 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package varouter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newWalkTestVarouter(t *testing.T) *Varouter {
+	vr := New()
+	for _, template := range []string{
+		"/+",
+		"/home/:user/.config/:application",
+		"/home/:user/.local/share",
+		"!/static/*.js",
+		"^/admin/+",
+	} {
+		if err := vr.Register(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vr
+}
+
+func walkString(t *testing.T, walk func(fn func(node NodeInfo) error) error) string {
+	var sb strings.Builder
+	if err := walk(func(node NodeInfo) error {
+		fmt.Fprintf(&sb, "%s%s %s %s '%s' %t %t\n", strings.Repeat(" ", node.Depth),
+			node.Name, node.Kind, node.Path, node.Template, node.Override, node.Exclusion)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestWalk(t *testing.T) {
+	const expected = `/ prefix / '/+' false false
+/admin literal /admin '' false false
+ / prefix /admin/ '^/admin/+' false true
+/home literal /home '' false false
+ /:user variable /home/:user '' false false
+  /.config literal /home/:user/.config '' false false
+   /:application variable /home/:user/.config/:application '/home/:user/.config/:application' false false
+  /.local literal /home/:user/.local '' false false
+   /share literal /home/:user/.local/share '/home/:user/.local/share' false false
+/static literal /static '' false false
+ /*.js wildcard /static/*.js '!/static/*.js' true false
+`
+	vr := newWalkTestVarouter(t)
+	if s := walkString(t, vr.Walk); s != expected {
+		t.Fatalf("Walk failed:\n%s", s)
+	}
+	vr.SetBackend(BackendRadix)
+	vr.Freeze()
+	if s := walkString(t, vr.Walk); s != expected {
+		t.Fatalf("Walk radix failed:\n%s", s)
+	}
+	if s := walkString(t, vr.Compile().Walk); s != expected {
+		t.Fatalf("Walk compiled failed:\n%s", s)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	vr := newWalkTestVarouter(t)
+	var paths []string
+	if err := vr.Walk(func(node NodeInfo) error {
+		paths = append(paths, node.Path)
+		if node.Kind == NodeVariable {
+			return SkipSubtree
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(paths) != "[/ /admin /admin/ /home /home/:user /static /static/*.js]" {
+		t.Fatalf("Walk SkipSubtree failed: '%v'", paths)
+	}
+	errStop := errors.New("stop")
+	paths = nil
+	if err := vr.Walk(func(node NodeInfo) error {
+		paths = append(paths, node.Path)
+		if node.Terminal {
+			return errStop
+		}
+		return nil
+	}); err != errStop || len(paths) != 1 {
+		t.Fatalf("Walk stop failed: '%v', '%v'", err, paths)
+	}
+}